@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/robert7528/hycore/config"
+	"github.com/robert7528/hycore/database"
+	"github.com/spf13/cobra"
+
+	"github.com/hysp/hyadmin-api/internal/keyrotation"
+)
+
+func cryptoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "crypto",
+		Short: "PII encryption key maintenance",
+		Long: `PII encryption key maintenance.
+
+To rotate the Tink key: add a new key to TINK_KEYSET and make it primary while
+keeping the old keys, restart the API so new writes use the new key, then run
+"hyadmin crypto rotate". Old keys can be removed from the keyset once
+"hyadmin crypto report" shows no stale values.`,
+	}
+	cmd.AddCommand(cryptoRotateCmd())
+	cmd.AddCommand(cryptoReportCmd())
+	return cmd
+}
+
+func cryptoRotateCmd() *cobra.Command {
+	var batch int
+	var restart bool
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt all encrypted columns with the primary key of TINK_KEYSET",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := newKeyRotation()
+			if err != nil {
+				return fmt.Errorf("rotate: %w", err)
+			}
+			fmt.Printf("Rotating to key %d...\n", svc.PrimaryKeyID())
+			err = svc.Rotate(batch, restart, func(p *keyrotation.Progress) {
+				fmt.Printf("  %s: up to id %d, %d re-encrypted\n", p.Target, p.LastID, p.Rotated)
+			})
+			if err != nil {
+				return fmt.Errorf("rotate: %w", err)
+			}
+			return printKeyReport(svc, batch)
+		},
+	}
+	cmd.Flags().IntVar(&batch, "batch", 500, "Rows per batch")
+	cmd.Flags().BoolVar(&restart, "restart", false, "Ignore saved progress and rescan all rows")
+	return cmd
+}
+
+func cryptoReportCmd() *cobra.Command {
+	var batch int
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Count encrypted values per key ID and those not yet on the primary key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := newKeyRotation()
+			if err != nil {
+				return fmt.Errorf("report: %w", err)
+			}
+			return printKeyReport(svc, batch)
+		},
+	}
+	cmd.Flags().IntVar(&batch, "batch", 1000, "Rows per batch")
+	return cmd
+}
+
+func newKeyRotation() (*keyrotation.Service, error) {
+	cfg := config.Load()
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect DB: %w", err)
+	}
+	return keyrotation.NewService(keyrotation.NewRepository(db), cfg.Tink.Keyset)
+}
+
+func printKeyReport(svc *keyrotation.Service, batch int) error {
+	reports, err := svc.Report(batch)
+	if err != nil {
+		return err
+	}
+	var stale int64
+	fmt.Printf("Primary key: %d\n", svc.PrimaryKeyID())
+	for _, r := range reports {
+		fmt.Printf("  %-35s total %-8d stale %d\n", r.Target, r.Total, r.Stale)
+		keys := make([]string, 0, len(r.ByKey))
+		for k := range r.ByKey {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("    key %-12s %d\n", k, r.ByKey[k])
+		}
+		stale += r.Stale
+	}
+	if stale > 0 {
+		fmt.Printf("%d values are still on old keys; run \"hyadmin crypto rotate\" before removing them.\n", stale)
+	} else {
+		fmt.Println("All values are on the primary key.")
+	}
+	return nil
+}
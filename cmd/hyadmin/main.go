@@ -14,6 +14,8 @@ func main() {
 	root.AddCommand(serveCmd())
 	root.AddCommand(migrateCmd())
 	root.AddCommand(seedCmd())
+	root.AddCommand(usersCmd())
+	root.AddCommand(cryptoCmd())
 	if err := root.Execute(); err != nil {
 		log.Fatal(err)
 	}
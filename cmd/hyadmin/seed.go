@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/blindindex"
 	"github.com/hysp/hyadmin-api/internal/feature"
 	"github.com/hysp/hyadmin-api/internal/pbmodule"
 	"github.com/hysp/hyadmin-api/internal/permission"
@@ -14,6 +15,7 @@ import (
 	"github.com/robert7528/hycore/crypto"
 	"github.com/robert7528/hycore/database"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -36,7 +38,12 @@ func seedCmd() *cobra.Command {
 				return fmt.Errorf("seed: init encryptor: %w", err)
 			}
 
-			if err := runSeed(db, enc); err != nil {
+			idx, err := blindindex.NewFromEnv(cfg, zap.NewNop())
+			if err != nil {
+				return fmt.Errorf("seed: init blind index: %w", err)
+			}
+
+			if err := runSeed(db, enc, idx); err != nil {
 				return fmt.Errorf("seed: %w", err)
 			}
 			fmt.Println("=== [seed] Completed successfully ===")
@@ -55,7 +62,7 @@ type casbinRule struct {
 	V5    string `gorm:"column:v5"`
 }
 
-func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
+func runSeed(db *gorm.DB, enc crypto.Encryptor, idx *blindindex.Indexer) error {
 	now := time.Now()
 
 	// ── 1. System tenant ──────────────────────────────────────
@@ -86,6 +93,7 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 		Username:       "admin",
 		PasswordHash:   string(hash),
 		DisplayNameEnc: displayNameEnc,
+		SearchIndex:    idx.Terms("系統管理員"),
 		Provider:       "local",
 		Enabled:        true,
 	}
@@ -193,8 +201,10 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 
 	// ── 5. User → Role assignment ──────────────────────────────
 	userRole := role.UserRole{
-		UserID: adminUser.ID,
-		RoleID: superRole.ID,
+		UserID:     adminUser.ID,
+		RoleID:     superRole.ID,
+		TenantCode: superRole.TenantCode,
+		Active:     true,
 	}
 	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
 		Create(&userRole).Error; err != nil {
@@ -204,8 +214,8 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 
 	// ── 6. Casbin base policies ────────────────────────────────
 	baseCasbinRules := []casbinRule{
-		{Ptype: "g", V0: fmt.Sprintf("user:%d", adminUser.ID), V1: fmt.Sprintf("role:%d", superRole.ID)},
-		{Ptype: "p", V0: fmt.Sprintf("role:%d", superRole.ID), V1: "*", V2: "access"},
+		{Ptype: "g", V0: fmt.Sprintf("user:%d", adminUser.ID), V1: fmt.Sprintf("role:%d", superRole.ID), V2: superRole.TenantCode},
+		{Ptype: "p", V0: fmt.Sprintf("role:%d", superRole.ID), V1: superRole.TenantCode, V2: "*", V3: "access", V4: "allow"},
 	}
 	for _, r := range baseCasbinRules {
 		if err := db.Table("hyadmin_casbin_rules").
@@ -213,7 +223,7 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 			Create(&r).Error; err != nil {
 			return fmt.Errorf("insert casbin rule ptype=%s v0=%s: %w", r.Ptype, r.V0, err)
 		}
-		fmt.Printf("  casbin: ptype=%s v0=%s v1=%s v2=%s\n", r.Ptype, r.V0, r.V1, r.V2)
+		fmt.Printf("  casbin: ptype=%s v0=%s v1=%s v2=%s v3=%s v4=%s\n", r.Ptype, r.V0, r.V1, r.V2, r.V3, r.V4)
 	}
 
 	// ── 7. Application settings ────────────────────────────────
@@ -227,8 +237,37 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 	}
 	settings := []setting{
 		{"auth.session.expire_hours", "24", "integer", "auth", "Session 有效時數", false},
+		{"auth.access_token.expire_minutes", "15", "integer", "auth", "Access token 有效分鐘數", false},
+		{"auth.mfa.required", "false", "boolean", "auth", "強制使用者啟用 MFA（可依租戶覆寫）", false},
+		{"auth.lockout.max_attempts", "5", "integer", "auth", "同一帳號連續登入失敗幾次後鎖定", false},
+		{"auth.lockout.ip_max_attempts", "50", "integer", "auth", "同一來源 IP 連續登入失敗幾次後鎖定", false},
+		{"auth.lockout.base_seconds", "30", "integer", "auth", "首次鎖定秒數（之後每次失敗加倍）", false},
+		{"auth.lockout.max_seconds", "3600", "integer", "auth", "最長鎖定秒數", false},
+		{"auth.lockout.window_minutes", "15", "integer", "auth", "失敗次數累計時間窗（分鐘）", false},
 		{"auth.password.min_length", "8", "integer", "auth", "密碼最短長度", false},
 		{"auth.password.require_uppercase", "true", "boolean", "auth", "密碼須包含大寫字母", false},
+		{"auth.password.require_lowercase", "false", "boolean", "auth", "密碼須包含小寫字母", false},
+		{"auth.password.require_digit", "false", "boolean", "auth", "密碼須包含數字", false},
+		{"auth.password.require_symbol", "false", "boolean", "auth", "密碼須包含符號", false},
+		{"auth.password.deny_common", "true", "boolean", "auth", "禁止使用常見密碼", false},
+		{"auth.password.history_count", "5", "integer", "auth", "不可重複使用最近幾組密碼（0 = 不檢查）", false},
+		{"auth.password.max_age_days", "0", "integer", "auth", "密碼有效天數（0 = 永不過期）", false},
+		{"auth.password.reset_expire_minutes", "30", "integer", "auth", "密碼重設連結有效分鐘數", false},
+		{"auth.password.reset_cooldown_seconds", "60", "integer", "auth", "同一帳號重送密碼重設信的最短間隔秒數", false},
+		{"auth.password.reset_url", "/reset-password?token={token}", "string", "auth", "密碼重設連結（{token} 會被替換）", false},
+		{"auth.invitation.expire_hours", "72", "integer", "auth", "邀請連結有效小時數（可依租戶覆寫）", false},
+		{"auth.invitation.url", "/accept-invitation?token={token}", "string", "auth", "邀請連結（{token} 會被替換）", false},
+		{"auth.provisioning.jit_enabled", "true", "boolean", "auth", "外部身分提供者首次登入時自動建立使用者（可依租戶覆寫）", false},
+		{"auth.pat.max_days", "365", "integer", "auth", "個人存取權杖最長有效天數（可依租戶覆寫）", false},
+		{"auth.service_account.token_expire_minutes", "15", "integer", "auth", "服務帳號 access token 有效分鐘數（可依租戶覆寫）", false},
+		{"auth.impersonation.expire_minutes", "30", "integer", "auth", "模擬登入 token 有效分鐘數（不可續期）", false},
+		{"users.import.max_rows", "5000", "integer", "users", "單次匯入使用者的最大筆數", false},
+		{"mail.driver", "none", "string", "mail", "寄信方式：none（不寄信）| log（僅限開發，信件內容含連結會寫入日誌）| file | smtp", false},
+		{"mail.from", "no-reply@localhost", "string", "mail", "寄件者地址", false},
+		{"mail.file.dir", "outbox", "string", "mail", "file 模式的信件輸出目錄", false},
+		{"mail.smtp.host", "localhost", "string", "mail", "SMTP 主機（密碼請用 MAIL_SMTP_PASSWORD 環境變數）", false},
+		{"mail.smtp.port", "587", "integer", "mail", "SMTP 埠號", false},
+		{"mail.smtp.username", "", "string", "mail", "SMTP 帳號", false},
 		{"audit.log.retention_days", "90", "integer", "audit", "稽核日誌保留天數", false},
 		{"ui.platform_name", "HySP Admin", "string", "ui", "平台顯示名稱", true},
 		{"ui.logo_url", "", "string", "ui", "Logo URL", true},
@@ -325,6 +364,11 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 		{"user-list", "users.list.update", "編輯使用者", `{"zh-TW":"編輯使用者","en":"Edit User"}`, "button", 3},
 		{"user-list", "users.list.delete", "刪除使用者", `{"zh-TW":"刪除使用者","en":"Delete User"}`, "button", 4},
 		{"user-list", "users.list.change_password", "修改密碼", `{"zh-TW":"修改密碼","en":"Change Password"}`, "button", 5},
+		{"user-list", "users.list.impersonate", "模擬登入", `{"zh-TW":"模擬登入","en":"Impersonate"}`, "button", 6},
+		{"user-list", "users.list.import", "匯入使用者", `{"zh-TW":"匯入使用者","en":"Import Users"}`, "button", 7},
+		{"user-list", "users.list.export", "匯出使用者", `{"zh-TW":"匯出使用者","en":"Export Users"}`, "button", 8},
+		{"user-list", "users.list.personal_data", "匯出個人資料", `{"zh-TW":"匯出個人資料","en":"Export Personal Data"}`, "button", 9},
+		{"user-list", "users.list.erase", "清除個人資料", `{"zh-TW":"清除個人資料","en":"Erase Personal Data"}`, "button", 10},
 		// rbac
 		{"role-list", "rbac.roles.view", "角色管理頁面", `{"zh-TW":"角色管理頁面","en":"Role Management"}`, "menu", 1},
 		{"role-list", "rbac.roles.create", "新增角色", `{"zh-TW":"新增角色","en":"Create Role"}`, "button", 2},
@@ -405,8 +449,10 @@ func runSeed(db *gorm.DB, enc crypto.Encryptor) error {
 		rule := casbinRule{
 			Ptype: "p",
 			V0:    fmt.Sprintf("role:%d", superRole.ID),
-			V1:    perm.Code,
-			V2:    "access",
+			V1:    superRole.TenantCode,
+			V2:    perm.Code,
+			V3:    "access",
+			V4:    "allow",
 		}
 		if err := db.Table("hyadmin_casbin_rules").
 			Clauses(clause.OnConflict{DoNothing: true}).
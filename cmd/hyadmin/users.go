@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/robert7528/hycore/config"
+	"github.com/robert7528/hycore/crypto"
+	"github.com/robert7528/hycore/database"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/blindindex"
+)
+
+func usersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Admin user maintenance",
+	}
+	cmd.AddCommand(usersReindexCmd())
+	return cmd
+}
+
+func usersReindexCmd() *cobra.Command {
+	var batch int
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Recompute the blind indexes of all users (after changing " + blindindex.KeyEnv + ")",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.Load()
+			db, err := database.Connect(cfg)
+			if err != nil {
+				return fmt.Errorf("reindex: connect DB: %w", err)
+			}
+			enc, err := crypto.New(cfg.Tink.Keyset)
+			if err != nil {
+				return fmt.Errorf("reindex: init encryptor: %w", err)
+			}
+			idx, err := blindindex.NewFromEnv(cfg, zap.NewNop())
+			if err != nil {
+				return fmt.Errorf("reindex: init blind index: %w", err)
+			}
+			// Reindex only reads and writes user rows; sessions and password policy are not needed.
+			svc := adminuser.NewService(adminuser.NewRepository(db), enc, idx, nil, nil)
+			done, err := svc.Reindex(batch, func(done int) {
+				fmt.Printf("  reindexed %d users\n", done)
+			})
+			if err != nil {
+				return fmt.Errorf("reindex: %w", err)
+			}
+			fmt.Printf("Reindexed %d users.\n", done)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batch, "batch", 500, "Users per batch")
+	return cmd
+}
@@ -1,32 +1,77 @@
 package adminuser
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"github.com/robert7528/hycore/middleware"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/tenant"
 )
 
 type Handler struct {
-	svc *Service
+	svc   *Service
+	audit *auditlog.Service
 }
 
-func NewHandler(svc *Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *Service, audit *auditlog.Service) *Handler {
+	return &Handler{svc: svc, audit: audit}
 }
 
 // List GET /api/v1/admin/users?tenant_code=...&page=1&page_size=20
+// Filters: q, email, enabled, provider, role_id, created_after (RFC 3339 or
+// YYYY-MM-DD); sort: id|username|provider|enabled|created_at|updated_at, "-" for descending.
 func (h *Handler) List(c *gin.Context) {
-	tenantCode := c.Query("tenant_code")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
+	f := &ListFilter{
+		TenantCode: c.Query("tenant_code"),
+		Q:          c.Query("q"),
+		Email:      c.Query("email"),
+		Provider:   c.Query("provider"),
+		Sort:       c.Query("sort"),
+	}
+	f.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	f.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 || f.PageSize > 100 {
+		f.PageSize = 20
+	}
+	if v := c.Query("enabled"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid enabled"})
+			return
+		}
+		f.Enabled = &enabled
+	}
+	if v := c.Query("role_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role_id"})
+			return
+		}
+		f.RoleID = uint(id)
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	if v := c.Query("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid created_after"})
+				return
+			}
+		}
+		f.CreatedAfter = &t
 	}
-	users, total, err := h.svc.List(tenantCode, page, pageSize)
+	users, total, err := h.svc.List(f)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -43,6 +88,9 @@ func (h *Handler) Create(c *gin.Context) {
 	}
 	dto, err := h.svc.Create(&req)
 	if err != nil {
+		if password.WritePolicyError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -83,23 +131,92 @@ func (h *Handler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "updated"})
 }
 
-// ChangePassword PUT /api/v1/admin/users/:id/password
-func (h *Handler) ChangePassword(c *gin.Context) {
+// ResetPassword PUT /api/v1/admin/users/:id/password
+// The route runs behind RequireTenantAccess: only platform admins may reset
+// passwords of users in other tenants.
+func (h *Handler) ResetPassword(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	var req ChangePasswordRequest
+	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.ChangePassword(uint(id), &req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	temp, err := h.svc.AdminResetPassword(uint(id), &req)
+	if err != nil {
+		if password.WritePolicyError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, ErrResetMode), errors.Is(err, ErrNotLocalUser), errors.Is(err, ErrPasswordReused):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+	h.recordReset(c, uint(id), req.Generate)
+	resp := gin.H{"message": "password reset", "must_change_password": true}
+	if temp != "" {
+		resp["temporary_password"] = temp
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// recordReset audits the reset under the acting admin; the password itself is never logged.
+func (h *Handler) recordReset(c *gin.Context, targetID uint, generated bool) {
+	actor := middleware.GetClaims(c)
+	if actor == nil {
+		return
+	}
+	d := map[string]interface{}{"target_user_id": targetID, "generated": generated}
+	if target, err := h.svc.GetByID(targetID); err == nil {
+		d["target_tenant_code"] = target.TenantCode
+		d["target_username"] = target.Username
+	}
+	detail, _ := json.Marshal(d)
+	h.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: actor.TenantCode,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		Action:     "PASSWORD_RESET",
+		Resource:   "users",
+		ResourceID: strconv.FormatUint(uint64(targetID), 10),
+		Detail:     string(detail),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+}
+
+// RequireTenantAccess aborts with 404 unless the user named by :id exists,
+// soft-deleted users included, and with 403 unless the caller may act on
+// its tenant. Use it on /admin/users/:id routes served by other packages.
+func (h *Handler) RequireTenantAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			c.Abort()
+			return
+		}
+		u, err := h.svc.repo.FindByIDUnscoped(uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			c.Abort()
+			return
+		}
+		if !tenant.CanAccess(c, u.TenantCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
 }
 
 // UpdateSelf updates display name for the authenticated user (profile endpoint).
@@ -109,7 +226,11 @@ func (h *Handler) UpdateSelf(c *gin.Context, userID uint, displayName string) er
 
 // ChangeSelfPassword changes the password for the authenticated user (profile endpoint).
 func (h *Handler) ChangeSelfPassword(c *gin.Context, userID uint, req *ChangePasswordRequest) error {
-	return h.svc.ChangePassword(userID, req)
+	var sessionID string
+	if claims := middleware.GetClaims(c); claims != nil {
+		sessionID = claims.ID
+	}
+	return h.svc.ChangeOwnPassword(userID, sessionID, req)
 }
 
 // Delete DELETE /api/v1/admin/users/:id
@@ -6,36 +6,56 @@ import (
 	"gorm.io/gorm"
 )
 
-func (AdminUser) TableName() string { return "hyadmin_users" }
+func (AdminUser) TableName() string       { return "hyadmin_users" }
+func (PasswordHistory) TableName() string { return "hyadmin_password_history" }
 
 // AdminUser stores platform administrators.
 // PII fields (display_name, email) are encrypted with Tink before persisting.
 type AdminUser struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	TenantCode     string         `gorm:"index;not null" json:"tenant_code"`
-	Username       string         `gorm:"uniqueIndex:uk_tenant_user;not null" json:"username"`
-	PasswordHash   string         `json:"-"`                      // bcrypt; empty for third-party logins
-	DisplayNameEnc string         `gorm:"column:display_name" json:"-"` // Tink-encrypted
-	EmailEnc       string         `gorm:"column:email" json:"-"`        // Tink-encrypted
-	Provider       string         `gorm:"default:'local'" json:"provider"` // local|google|...
-	ProviderID     string         `json:"provider_id,omitempty"`
-	Enabled        bool           `gorm:"default:true" json:"enabled"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	TenantCode         string         `gorm:"index;not null" json:"tenant_code"`
+	Username           string         `gorm:"uniqueIndex:uk_tenant_user;not null" json:"username"`
+	PasswordHash       string         `json:"-"`                                        // bcrypt; empty for third-party logins
+	DisplayNameEnc     string         `gorm:"column:display_name" json:"-"`             // Tink-encrypted
+	EmailEnc           string         `gorm:"column:email" json:"-"`                    // Tink-encrypted
+	EmailIndex         string         `gorm:"column:email_bidx;size:64;index" json:"-"` // blind index of email
+	SearchIndex        string         `gorm:"column:search_bidx;type:text" json:"-"`    // blind index terms of display name and email
+	Provider           string         `gorm:"default:'local'" json:"provider"`          // local|google|...
+	ProviderID         string         `json:"provider_id,omitempty"`
+	Enabled            bool           `gorm:"default:true" json:"enabled"`
+	TOTPSecretEnc      string         `gorm:"column:totp_secret" json:"-"` // Tink-encrypted; set on setup, active once MFAEnabled
+	TOTPLastStep       int64          `gorm:"default:0" json:"-"`          // last accepted TOTP time step (replay protection)
+	MFAEnabled         bool           `gorm:"column:mfa_enabled;default:false" json:"mfa_enabled"`
+	MustChangePassword bool           `gorm:"default:false" json:"must_change_password"`
+	PasswordChangedAt  *time.Time     `json:"password_changed_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PasswordHistory keeps previous bcrypt hashes so recent passwords cannot be reused.
+type PasswordHistory struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"index;not null"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
 }
 
 // AdminUserDTO is the decrypted representation returned to callers.
 type AdminUserDTO struct {
-	ID          uint      `json:"id"`
-	TenantCode  string    `json:"tenant_code"`
-	Username    string    `json:"username"`
-	DisplayName string    `json:"display_name"`
-	Email       string    `json:"email"`
-	Provider    string    `json:"provider"`
-	Enabled     bool      `json:"enabled"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 uint       `json:"id"`
+	TenantCode         string     `json:"tenant_code"`
+	Username           string     `json:"username"`
+	DisplayName        string     `json:"display_name"`
+	Email              string     `json:"email"`
+	Provider           string     `json:"provider"`
+	ProviderID         string     `json:"provider_id,omitempty"`
+	Enabled            bool       `json:"enabled"`
+	MFAEnabled         bool       `json:"mfa_enabled"`
+	MustChangePassword bool       `json:"must_change_password"`
+	PasswordChangedAt  *time.Time `json:"password_changed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
@@ -46,6 +66,23 @@ type CreateUserRequest struct {
 	Email       string `json:"email"`
 	Provider    string `json:"provider"`
 	ProviderID  string `json:"provider_id"`
+	// MustChangePassword forces a password change on first login; defaults to true when Password is set.
+	MustChangePassword *bool `json:"must_change_password"`
+}
+
+// ListFilter narrows GET /admin/users. Q matches every word against the
+// username and the blind index of display name and email; Email is exact.
+type ListFilter struct {
+	TenantCode   string
+	Q            string
+	Email        string
+	Enabled      *bool
+	Provider     string
+	RoleID       uint
+	CreatedAfter *time.Time
+	Sort         string // column, "-" prefix for descending; see sortColumns
+	Page         int
+	PageSize     int
 }
 
 type UpdateUserRequest struct {
@@ -58,3 +95,10 @@ type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required"`
 }
+
+// ResetPasswordRequest is the admin reset: either set NewPassword or ask the
+// server to Generate a temporary one. The user must change it on next login.
+type ResetPasswordRequest struct {
+	NewPassword string `json:"new_password"`
+	Generate    bool   `json:"generate"`
+}
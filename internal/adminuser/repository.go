@@ -1,7 +1,12 @@
 package adminuser
 
 import (
+	"fmt"
+	"strings"
+
 	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/blindindex"
 )
 
 type Repository struct {
@@ -22,25 +27,128 @@ func (r *Repository) FindByID(id uint) (*AdminUser, error) {
 	return &u, err
 }
 
+// FindByIDUnscoped also finds soft-deleted users.
+func (r *Repository) FindByIDUnscoped(id uint) (*AdminUser, error) {
+	var u AdminUser
+	err := r.db.Unscoped().First(&u, id).Error
+	return &u, err
+}
+
 func (r *Repository) FindByUsername(tenantCode, username string) (*AdminUser, error) {
 	var u AdminUser
 	err := r.db.Where("tenant_code = ? AND username = ? AND deleted_at IS NULL", tenantCode, username).First(&u).Error
 	return &u, err
 }
 
-func (r *Repository) List(tenantCode string, page, pageSize int) ([]AdminUser, int64, error) {
+// FindByProvider finds the user linked to an external identity.
+func (r *Repository) FindByProvider(tenantCode, provider, providerID string) (*AdminUser, error) {
+	var u AdminUser
+	err := r.db.Where("tenant_code = ? AND provider = ? AND provider_id = ?", tenantCode, provider, providerID).First(&u).Error
+	return &u, err
+}
+
+// sortColumns are the columns a list may be sorted by; PII is encrypted and cannot be sorted.
+var sortColumns = map[string]bool{
+	"id": true, "username": true, "provider": true, "enabled": true, "created_at": true, "updated_at": true,
+}
+
+// List applies f; words are the blind-index query of f.Q (see blindindex.Indexer.Query).
+func (r *Repository) List(f *ListFilter, emailIndex string, words []blindindex.Word) ([]AdminUser, int64, error) {
 	var users []AdminUser
 	var total int64
-	q := r.db.Model(&AdminUser{}).Where("tenant_code = ?", tenantCode)
-	q.Count(&total)
-	err := q.Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	q := r.db.Model(&AdminUser{}).Where("tenant_code = ?", f.TenantCode)
+	for _, w := range words {
+		q = q.Where("(username ILIKE ? OR string_to_array(search_bidx, ' ') @> ARRAY[?]::text[])", "%"+w.Text+"%", w.Terms)
+	}
+	if f.Email != "" {
+		q = q.Where("email_bidx = ?", emailIndex)
+	}
+	if f.Enabled != nil {
+		q = q.Where("enabled = ?", *f.Enabled)
+	}
+	if f.Provider != "" {
+		q = q.Where("provider = ?", f.Provider)
+	}
+	if f.RoleID != 0 {
+		// Direct holders of the role in the user's own tenant domain (Casbin
+		// g = sub, role, dom; see role.UserSubject).
+		q = q.Where("id IN (SELECT CAST(SUBSTRING(v0 FROM 6) AS BIGINT) FROM hyadmin_casbin_rules "+
+			"WHERE ptype = 'g' AND v0 LIKE 'user:%' AND v1 = ? AND v2 = hyadmin_users.tenant_code)", fmt.Sprintf("role:%d", f.RoleID))
+	}
+	if f.CreatedAfter != nil {
+		q = q.Where("created_at >= ?", *f.CreatedAfter)
+	}
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	order := "id"
+	if col := strings.TrimPrefix(f.Sort, "-"); sortColumns[col] {
+		order = col
+		if strings.HasPrefix(f.Sort, "-") {
+			order += " DESC"
+		}
+		if col != "id" {
+			order += ", id"
+		}
+	}
+	err := q.Order(order).Offset((f.Page - 1) * f.PageSize).Limit(f.PageSize).Find(&users).Error
 	return users, total, err
 }
 
+// ListBatch returns up to n users with ID greater than afterID, across tenants.
+func (r *Repository) ListBatch(afterID uint, n int) ([]AdminUser, error) {
+	var users []AdminUser
+	err := r.db.Where("id > ?", afterID).Order("id").Limit(n).Find(&users).Error
+	return users, err
+}
+
+// ListTenantBatch returns up to n users of a tenant with ID greater than afterID.
+func (r *Repository) ListTenantBatch(tenantCode string, afterID uint, n int) ([]AdminUser, error) {
+	var users []AdminUser
+	err := r.db.Where("tenant_code = ? AND id > ?", tenantCode, afterID).Order("id").Limit(n).Find(&users).Error
+	return users, err
+}
+
+func (r *Repository) FindByIDs(tenantCode string, ids []uint) ([]AdminUser, error) {
+	var users []AdminUser
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("tenant_code = ? AND id IN ?", tenantCode, ids).Find(&users).Error
+	return users, err
+}
+
+func (r *Repository) ListAll(tenantCode string) ([]AdminUser, error) {
+	var users []AdminUser
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("id").Find(&users).Error
+	return users, err
+}
+
 func (r *Repository) Update(id uint, updates map[string]interface{}) error {
 	return r.db.Model(&AdminUser{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// ConsumeTOTPStep raises the last accepted TOTP step to step; it reports false
+// when an equal or later step was already used, i.e. the code is a replay.
+func (r *Repository) ConsumeTOTPStep(id uint, step int64) (bool, error) {
+	res := r.db.Model(&AdminUser{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *Repository) AddPasswordHistory(userID uint, hash string) error {
+	return r.db.Create(&PasswordHistory{UserID: userID, PasswordHash: hash}).Error
+}
+
+// RecentPasswordHashes returns up to n most recent password hashes, newest first.
+func (r *Repository) RecentPasswordHashes(userID uint, n int) ([]string, error) {
+	var hashes []string
+	err := r.db.Model(&PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(n).Pluck("password_hash", &hashes).Error
+	return hashes, err
+}
+
 func (r *Repository) Delete(id uint) error {
 	return r.db.Delete(&AdminUser{}, id).Error
 }
@@ -0,0 +1,92 @@
+package adminuser
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection would get its own :memory: database
+	if err := db.AutoMigrate(&AdminUser{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository(db)
+}
+
+func TestConsumeTOTPStep(t *testing.T) {
+	repo := newTestRepository(t)
+	u := &AdminUser{TenantCode: "acme", Username: "alice", TOTPLastStep: 100}
+	if err := repo.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		step int64
+		want bool
+	}{
+		{"older step", 99, false},
+		{"last used step", 100, false},
+		{"next step", 101, true},
+		{"same step again", 101, false},
+		{"skipped ahead", 103, true},
+		{"back within skew", 102, false},
+	}
+	for _, tt := range tests {
+		got, err := repo.ConsumeTOTPStep(u.ID, tt.step)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: ConsumeTOTPStep(%d) = %v, want %v", tt.name, tt.step, got, tt.want)
+		}
+	}
+}
+
+// TestConsumeTOTPStepConcurrent presents one code from many requests at once;
+// exactly one of them may accept it.
+func TestConsumeTOTPStepConcurrent(t *testing.T) {
+	repo := newTestRepository(t)
+	u := &AdminUser{TenantCode: "acme", Username: "alice"}
+	if err := repo.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.ConsumeTOTPStep(u.ID, 42)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Fatalf("the code was accepted %d times, want once", accepted)
+	}
+}
@@ -1,19 +1,41 @@
 package adminuser
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/robert7528/hycore/crypto"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hysp/hyadmin-api/internal/blindindex"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+var (
+	ErrUserNotFound    = errors.New("adminuser: user not found")
+	ErrUserDisabled    = errors.New("adminuser: user disabled")
+	ErrInvalidPassword = errors.New("adminuser: invalid password")
+	ErrPasswordReused  = errors.New("adminuser: password was used recently")
+	ErrNotLocalUser    = errors.New("adminuser: user does not sign in with a local password")
+	ErrResetMode       = errors.New("adminuser: specify exactly one of new_password or generate")
 )
 
+// dummyHash is compared against when the user does not exist or has no local
+// password, so neither case answers faster than a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("hyadmin-dummy-password"), bcrypt.DefaultCost)
+
 type Service struct {
 	repo      *Repository
 	encryptor crypto.Encryptor
+	index     *blindindex.Indexer
+	sessions  *session.Service
+	passwords *password.Service
 }
 
-func NewService(repo *Repository, enc crypto.Encryptor) *Service {
-	return &Service{repo: repo, encryptor: enc}
+func NewService(repo *Repository, enc crypto.Encryptor, index *blindindex.Indexer, sessions *session.Service, passwords *password.Service) *Service {
+	return &Service{repo: repo, encryptor: enc, index: index, sessions: sessions, passwords: passwords}
 }
 
 func (s *Service) Create(req *CreateUserRequest) (*AdminUserDTO, error) {
@@ -25,11 +47,18 @@ func (s *Service) Create(req *CreateUserRequest) (*AdminUserDTO, error) {
 		Enabled:    true,
 	}
 	if req.Password != "" {
+		if err := s.passwords.Validate(req.TenantCode, req.Username, req.Password); err != nil {
+			return nil, err
+		}
 		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return nil, fmt.Errorf("adminuser: hash password: %w", err)
 		}
+		now := time.Now()
 		u.PasswordHash = string(hash)
+		u.PasswordChangedAt = &now
+		// An admin-chosen password must be replaced on first login unless explicitly waived.
+		u.MustChangePassword = req.MustChangePassword == nil || *req.MustChangePassword
 	}
 	if req.Provider == "" {
 		u.Provider = "local"
@@ -43,9 +72,16 @@ func (s *Service) Create(req *CreateUserRequest) (*AdminUserDTO, error) {
 	if err != nil {
 		return nil, err
 	}
+	u.EmailIndex = s.index.Email(req.Email)
+	u.SearchIndex = s.index.Terms(req.DisplayName, req.Email)
 	if err := s.repo.Create(u); err != nil {
 		return nil, err
 	}
+	if u.PasswordHash != "" {
+		if err := s.repo.AddPasswordHistory(u.ID, u.PasswordHash); err != nil {
+			return nil, err
+		}
+	}
 	return s.toDTO(u)
 }
 
@@ -57,12 +93,33 @@ func (s *Service) GetByID(id uint) (*AdminUserDTO, error) {
 	return s.toDTO(u)
 }
 
+// GetByIDUnscoped is GetByID including soft-deleted users, whose PII is
+// kept until erased.
+func (s *Service) GetByIDUnscoped(id uint) (*AdminUserDTO, error) {
+	u, err := s.repo.FindByIDUnscoped(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toDTO(u)
+}
+
+// GetUser returns the stored row without decrypting PII, for hot paths that
+// only need identity and status.
+func (s *Service) GetUser(id uint) (*AdminUser, error) {
+	return s.repo.FindByID(id)
+}
+
 func (s *Service) GetByUsername(tenantCode, username string) (*AdminUser, error) {
 	return s.repo.FindByUsername(tenantCode, username)
 }
 
-func (s *Service) List(tenantCode string, page, pageSize int) ([]AdminUserDTO, int64, error) {
-	users, total, err := s.repo.List(tenantCode, page, pageSize)
+// GetByProvider returns the user linked to an external identity (provider + subject).
+func (s *Service) GetByProvider(tenantCode, provider, providerID string) (*AdminUser, error) {
+	return s.repo.FindByProvider(tenantCode, provider, providerID)
+}
+
+func (s *Service) List(f *ListFilter) ([]AdminUserDTO, int64, error) {
+	users, total, err := s.repo.List(f, s.index.Email(f.Email), s.index.Query(f.Q))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -77,6 +134,112 @@ func (s *Service) List(tenantCode string, page, pageSize int) ([]AdminUserDTO, i
 	return dtos, total, nil
 }
 
+// reindexUpdate adds the blind indexes for a display name or email change to updates.
+func (s *Service) reindexUpdate(id uint, req *UpdateUserRequest, updates map[string]interface{}) error {
+	dto, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	displayName, email := dto.DisplayName, dto.Email
+	if req.DisplayName != "" {
+		displayName = req.DisplayName
+	}
+	if req.Email != "" {
+		email = req.Email
+	}
+	updates["email_bidx"] = s.index.Email(email)
+	updates["search_bidx"] = s.index.Terms(displayName, email)
+	return nil
+}
+
+// Reindex recomputes the blind indexes of every user in batches, e.g. after
+// the index key changed. progress, if set, is called after each batch.
+func (s *Service) Reindex(batchSize int, progress func(done int)) (int, error) {
+	var afterID uint
+	done := 0
+	for {
+		users, err := s.repo.ListBatch(afterID, batchSize)
+		if err != nil {
+			return done, err
+		}
+		if len(users) == 0 {
+			return done, nil
+		}
+		for i := range users {
+			dto, err := s.toDTO(&users[i])
+			if err != nil {
+				return done, fmt.Errorf("adminuser: user %d: %w", users[i].ID, err)
+			}
+			if err := s.repo.Update(dto.ID, map[string]interface{}{
+				"email_bidx":  s.index.Email(dto.Email),
+				"search_bidx": s.index.Terms(dto.DisplayName, dto.Email),
+			}); err != nil {
+				return done, err
+			}
+			afterID = dto.ID
+			done++
+		}
+		if progress != nil {
+			progress(done)
+		}
+	}
+}
+
+// ListAll returns every user of a tenant, decrypted. Intended for callers that
+// filter on PII in memory, such as SCIM.
+func (s *Service) ListAll(tenantCode string) ([]AdminUserDTO, error) {
+	users, err := s.repo.ListAll(tenantCode)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]AdminUserDTO, 0, len(users))
+	for i := range users {
+		dto, err := s.toDTO(&users[i])
+		if err != nil {
+			return nil, err
+		}
+		dtos = append(dtos, *dto)
+	}
+	return dtos, nil
+}
+
+// ListTenantBatch returns up to n decrypted users of a tenant with ID greater
+// than afterID, for callers that page through a whole tenant such as export.
+func (s *Service) ListTenantBatch(tenantCode string, afterID uint, n int) ([]AdminUserDTO, error) {
+	users, err := s.repo.ListTenantBatch(tenantCode, afterID, n)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]AdminUserDTO, 0, len(users))
+	for i := range users {
+		dto, err := s.toDTO(&users[i])
+		if err != nil {
+			return nil, err
+		}
+		dtos = append(dtos, *dto)
+	}
+	return dtos, nil
+}
+
+// Usernames maps the given IDs of a tenant's users to their usernames; IDs
+// that do not exist or belong to another tenant are left out.
+func (s *Service) Usernames(tenantCode string, ids []uint) (map[uint]string, error) {
+	users, err := s.repo.FindByIDs(tenantCode, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return names, nil
+}
+
+// SetProviderID links a user to the subject of an external identity source.
+func (s *Service) SetProviderID(id uint, providerID string) error {
+	return s.repo.Update(id, map[string]interface{}{"provider_id": providerID})
+}
+
 func (s *Service) Update(id uint, req *UpdateUserRequest) error {
 	updates := make(map[string]interface{})
 	if req.DisplayName != "" {
@@ -96,7 +259,19 @@ func (s *Service) Update(id uint, req *UpdateUserRequest) error {
 	if req.Enabled != nil {
 		updates["enabled"] = *req.Enabled
 	}
-	return s.repo.Update(id, updates)
+	if req.DisplayName != "" || req.Email != "" {
+		if err := s.reindexUpdate(id, req, updates); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Update(id, updates); err != nil {
+		return err
+	}
+	// Disabling a user ends all of their sessions immediately.
+	if req.Enabled != nil && !*req.Enabled {
+		return s.sessions.RevokeAllForUser(id, "user_disabled")
+	}
+	return nil
 }
 
 func (s *Service) ChangePassword(id uint, req *ChangePasswordRequest) error {
@@ -107,32 +282,174 @@ func (s *Service) ChangePassword(id uint, req *ChangePasswordRequest) error {
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.OldPassword)); err != nil {
 		return fmt.Errorf("adminuser: old password mismatch")
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	return s.setPassword(u, req.NewPassword, false)
+}
+
+// ChangeOwnPassword is the self-service change: on success the caller's
+// session loses its password_change restriction.
+func (s *Service) ChangeOwnPassword(id uint, sessionID string, req *ChangePasswordRequest) error {
+	if err := s.ChangePassword(id, req); err != nil {
+		return err
+	}
+	if sessionID == "" {
+		return nil
+	}
+	return s.sessions.RemoveScope(sessionID, session.ScopePasswordChange)
+}
+
+// ResetPassword sets a new password without checking the old one (policy and
+// history still apply) and ends all of the user's sessions.
+func (s *Service) ResetPassword(id uint, newPassword string, mustChange bool, reason string) error {
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.setPassword(u, newPassword, mustChange); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllForUser(id, reason)
+}
+
+// AdminResetPassword sets (or generates) a password for another user without
+// the old one. The user must change it on next login and all sessions end.
+// The generated password, if any, is returned so it can be handed over once.
+func (s *Service) AdminResetPassword(id uint, req *ResetPasswordRequest) (string, error) {
+	if (req.NewPassword == "") == !req.Generate {
+		return "", ErrResetMode
+	}
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return "", err
+	}
+	if u.Provider != "local" {
+		return "", ErrNotLocalUser
+	}
+	pw := req.NewPassword
+	if req.Generate {
+		if pw, err = s.passwords.Generate(u.TenantCode); err != nil {
+			return "", err
+		}
+	}
+	if err := s.ResetPassword(id, pw, true, "admin_password_reset"); err != nil {
+		return "", err
+	}
+	if req.Generate {
+		return pw, nil
+	}
+	return "", nil
+}
+
+// PasswordChangeRequired reports whether a local user must change their
+// password before using the API, either because it was flagged or has expired.
+func (s *Service) PasswordChangeRequired(id uint) (bool, error) {
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return false, err
+	}
+	if u.Provider != "local" || u.PasswordHash == "" {
+		return false, nil
+	}
+	return u.MustChangePassword || s.passwords.Expired(u.TenantCode, u.PasswordChangedAt), nil
+}
+
+// setPassword validates newPassword against the tenant policy and recent
+// history, then stores it and records it in the history.
+func (s *Service) setPassword(u *AdminUser, newPassword string, mustChange bool) error {
+	if err := s.passwords.Validate(u.TenantCode, u.Username, newPassword); err != nil {
+		return err
+	}
+	if n := s.passwords.HistoryCount(u.TenantCode); n > 0 {
+		recent, err := s.repo.RecentPasswordHashes(u.ID, n)
+		if err != nil {
+			return err
+		}
+		for _, h := range recent {
+			if bcrypt.CompareHashAndPassword([]byte(h), []byte(newPassword)) == nil {
+				return ErrPasswordReused
+			}
+		}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
-	return s.repo.Update(id, map[string]interface{}{"password_hash": string(hash)})
+	if err := s.repo.Update(u.ID, map[string]interface{}{
+		"password_hash":        string(hash),
+		"password_changed_at":  time.Now(),
+		"must_change_password": mustChange,
+	}); err != nil {
+		return err
+	}
+	return s.repo.AddPasswordHistory(u.ID, string(hash))
 }
 
 func (s *Service) Delete(id uint) error {
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllForUser(id, "user_deleted")
 }
 
-// VerifyPassword is used by LocalProvider.
+// VerifyPassword is used by LocalProvider. The returned user is non-nil
+// whenever the account exists, even on error, so callers can attribute failures.
 func (s *Service) VerifyPassword(tenantCode, username, password string) (*AdminUser, error) {
 	u, err := s.repo.FindByUsername(tenantCode, username)
 	if err != nil {
-		return nil, fmt.Errorf("adminuser: user not found")
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrUserNotFound
 	}
-	if !u.Enabled {
-		return nil, fmt.Errorf("adminuser: user disabled")
+	if u.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return u, ErrInvalidPassword
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
-		return nil, fmt.Errorf("adminuser: invalid password")
+		return u, ErrInvalidPassword
+	}
+	if !u.Enabled {
+		return u, ErrUserDisabled
 	}
 	return u, nil
 }
 
+// TOTPState returns the decrypted TOTP secret, whether MFA is active and the
+// last accepted time step.
+func (s *Service) TOTPState(id uint) (secret string, enabled bool, lastStep int64, err error) {
+	u, err := s.repo.FindByID(id)
+	if err != nil {
+		return "", false, 0, err
+	}
+	secret, err = s.encryptor.Decrypt(u.TOTPSecretEnc)
+	if err != nil {
+		return "", false, 0, err
+	}
+	return secret, u.MFAEnabled, u.TOTPLastStep, nil
+}
+
+// SetTOTPSecret stores a pending TOTP secret; MFA stays off until EnableMFA.
+func (s *Service) SetTOTPSecret(id uint, secret string) error {
+	enc, err := s.encryptor.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+	return s.repo.Update(id, map[string]interface{}{"totp_secret": enc, "totp_last_step": 0})
+}
+
+// ConsumeTOTPStep records step as used unless it, or a later one, already was.
+// The check and the write are a single statement, so concurrent requests
+// cannot both accept the same code.
+func (s *Service) ConsumeTOTPStep(id uint, step int64) (bool, error) {
+	return s.repo.ConsumeTOTPStep(id, step)
+}
+
+func (s *Service) EnableMFA(id uint) error {
+	return s.repo.Update(id, map[string]interface{}{"mfa_enabled": true})
+}
+
+// DisableMFA turns MFA off and discards the TOTP secret.
+func (s *Service) DisableMFA(id uint) error {
+	return s.repo.Update(id, map[string]interface{}{"mfa_enabled": false, "totp_secret": "", "totp_last_step": 0})
+}
+
 func (s *Service) toDTO(u *AdminUser) (*AdminUserDTO, error) {
 	dn, err := s.encryptor.Decrypt(u.DisplayNameEnc)
 	if err != nil {
@@ -143,14 +460,18 @@ func (s *Service) toDTO(u *AdminUser) (*AdminUserDTO, error) {
 		return nil, err
 	}
 	return &AdminUserDTO{
-		ID:          u.ID,
-		TenantCode:  u.TenantCode,
-		Username:    u.Username,
-		DisplayName: dn,
-		Email:       em,
-		Provider:    u.Provider,
-		Enabled:     u.Enabled,
-		CreatedAt:   u.CreatedAt,
-		UpdatedAt:   u.UpdatedAt,
+		ID:                 u.ID,
+		TenantCode:         u.TenantCode,
+		Username:           u.Username,
+		DisplayName:        dn,
+		Email:              em,
+		Provider:           u.Provider,
+		ProviderID:         u.ProviderID,
+		Enabled:            u.Enabled,
+		MFAEnabled:         u.MFAEnabled,
+		MustChangePassword: u.MustChangePassword,
+		PasswordChangedAt:  u.PasswordChangedAt,
+		CreatedAt:          u.CreatedAt,
+		UpdatedAt:          u.UpdatedAt,
 	}, nil
 }
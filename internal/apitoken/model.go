@@ -0,0 +1,47 @@
+package apitoken
+
+import "time"
+
+func (Token) TableName() string { return "hyadmin_api_tokens" }
+
+// Token is a personal access token. The secret is shown once at creation;
+// only its SHA-256 hash is stored. Codes is the subset of the owner's
+// permission codes the token may use.
+type Token struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	TenantCode string     `gorm:"not null" json:"tenant_code"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"` // first characters, to recognise a token in lists
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Codes      string     `gorm:"type:text;not null" json:"-"` // space-separated permission codes
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	PermissionCodes []string `gorm:"-" json:"permission_codes"`
+}
+
+type CreateRequest struct {
+	Name            string   `json:"name" binding:"required"`
+	PermissionCodes []string `json:"permission_codes" binding:"required,min=1"`
+	ExpiresInDays   int      `json:"expires_in_days"` // default 90, capped by auth.pat.max_days
+}
+
+// Created is returned once, with the plaintext token.
+type Created struct {
+	*Token
+	Secret string `json:"token"`
+}
+
+// Principal is the identity behind a verified token.
+type Principal struct {
+	TokenID    uint
+	UserID     uint
+	TenantCode string
+	Username   string
+	Provider   string
+	Codes      []string
+}
@@ -0,0 +1,50 @@
+package apitoken
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(t *Token) error {
+	return r.db.Create(t).Error
+}
+
+func (r *Repository) FindByHash(hash string) (*Token, error) {
+	var t Token
+	err := r.db.Where("token_hash = ?", hash).First(&t).Error
+	return &t, err
+}
+
+func (r *Repository) ListByUser(userID uint) ([]Token, error) {
+	var list []Token
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// Revoke revokes one of the user's tokens; it reports false if there was nothing to revoke.
+func (r *Repository) Revoke(userID, id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&Token{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) RevokeAllForUser(userID uint, now time.Time) error {
+	return r.db.Model(&Token{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+func (r *Repository) Touch(id uint, ip string, now time.Time) error {
+	return r.db.Model(&Token{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,198 @@
+// Package apitoken implements personal access tokens ("hya_..."): named,
+// expiring, revocable bearer tokens limited to a subset of the owner's
+// permission codes, for scripts and automation.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+// Prefix marks personal access tokens so AuthMiddleware can tell them from JWTs.
+const Prefix = "hya_"
+
+// touchInterval limits last-used bookkeeping to one write per token per minute.
+const touchInterval = time.Minute
+
+var (
+	ErrInvalidToken = errors.New("apitoken: invalid, expired or revoked token")
+	ErrNotFound     = errors.New("apitoken: token not found")
+	ErrCodeNotHeld  = errors.New("apitoken: permission code not held by owner")
+)
+
+type Service struct {
+	repo     *Repository
+	users    *adminuser.Service
+	roles    *role.Service
+	settings *setting.Service
+}
+
+func NewService(repo *Repository, users *adminuser.Service, roles *role.Service, settings *setting.Service) *Service {
+	return &Service{repo: repo, users: users, roles: roles, settings: settings}
+}
+
+// Create issues a token for userID. Every requested code must currently be
+// held by the owner, directly or through a pattern such as "*" or "cert.*".
+func (s *Service) Create(userID uint, tenantCode string, req *CreateRequest) (*Created, error) {
+	held, err := s.roles.GetPermissionCodesForUser(tenantCode, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCodes(held, req.PermissionCodes); err != nil {
+		return nil, err
+	}
+
+	days := req.ExpiresInDays
+	if days <= 0 {
+		days = 90
+	}
+	if max := s.settings.GetTenantInt(tenantCode, "auth.pat.max_days", 365); max > 0 && days > max {
+		days = max
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("apitoken: random: %w", err)
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(b)
+	t := &Token{
+		UserID:     userID,
+		TenantCode: tenantCode,
+		Name:       req.Name,
+		Prefix:     secret[:len(Prefix)+6],
+		TokenHash:  hashToken(secret),
+		Codes:      strings.Join(dedupe(req.PermissionCodes), " "),
+		ExpiresAt:  time.Now().Add(time.Duration(days) * 24 * time.Hour),
+	}
+	if err := s.repo.Create(t); err != nil {
+		return nil, err
+	}
+	t.PermissionCodes = strings.Fields(t.Codes)
+	return &Created{Token: t, Secret: secret}, nil
+}
+
+func (s *Service) List(userID uint) ([]Token, error) {
+	list, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].PermissionCodes = strings.Fields(list[i].Codes)
+	}
+	return list, nil
+}
+
+func (s *Service) Revoke(userID, id uint) error {
+	ok, err := s.repo.Revoke(userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) RevokeAllForUser(userID uint) error {
+	return s.repo.RevokeAllForUser(userID, time.Now())
+}
+
+// Verify resolves a presented token. The effective codes are the token's
+// codes still held by the owner, so removing a role also narrows the token.
+func (s *Service) Verify(secret, ip string) (*Principal, error) {
+	t, err := s.repo.FindByHash(hashToken(secret))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now()
+	if t.RevokedAt != nil || !now.Before(t.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	u, err := s.users.GetUser(t.UserID)
+	if err != nil || !u.Enabled {
+		return nil, ErrInvalidToken
+	}
+	held, err := s.roles.GetPermissionCodesForUser(u.TenantCode, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > touchInterval || t.LastUsedIP != ip {
+		_ = s.repo.Touch(t.ID, ip, now)
+	}
+	return &Principal{
+		TokenID:    t.ID,
+		UserID:     u.ID,
+		TenantCode: u.TenantCode,
+		Username:   u.Username,
+		Provider:   u.Provider,
+		Codes:      Effective(strings.Fields(t.Codes), held),
+	}, nil
+}
+
+// checkCodes rejects requested codes the user does not hold. A requested
+// pattern is matched literally against the held ones, so "user.*" is granted
+// by "user.*" or "*" but not by "user.view", and "*.view" not by "user.*".
+func checkCodes(held, requested []string) error {
+	for _, code := range requested {
+		if !permission.MatchAny(held, code) {
+			return fmt.Errorf("%w: %s", ErrCodeNotHeld, code)
+		}
+	}
+	return nil
+}
+
+// Effective intersects a token's codes with the owner's, honouring "*" on
+// either side and the owner's patterns.
+func Effective(token, held []string) []string {
+	switch {
+	case contains(held, "*"):
+		return token
+	case contains(token, "*"):
+		return held
+	}
+	out := make([]string, 0, len(token))
+	for _, c := range token {
+		if permission.MatchAny(held, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
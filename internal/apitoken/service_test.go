@@ -0,0 +1,80 @@
+package apitoken
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		held      []string
+		requested []string
+		ok        bool
+	}{
+		{"nothing requested", nil, nil, true},
+		{"nothing held", nil, []string{"user.view"}, false},
+		{"exact", []string{"user.view"}, []string{"user.view"}, true},
+		{"other code", []string{"user.view"}, []string{"user.edit"}, false},
+		{"code under module", []string{"user.*"}, []string{"user.view", "user.delete"}, true},
+		{"code under action", []string{"*.view"}, []string{"cert.key.view"}, true},
+		{"code under all", []string{"*"}, []string{"role.edit"}, true},
+		{"one of several not held", []string{"user.*"}, []string{"user.view", "role.view"}, false},
+		{"any of several held", []string{"role.view", "user.*"}, []string{"user.edit", "role.view"}, true},
+
+		// Patterns must be covered by a held pattern, not by codes they match.
+		{"same module", []string{"user.*"}, []string{"user.*"}, true},
+		{"module from all", []string{"*"}, []string{"user.*"}, true},
+		{"all from all", []string{"*"}, []string{"*"}, true},
+		{"module from its codes", []string{"user.view", "user.edit"}, []string{"user.*"}, false},
+		{"all from a module", []string{"user.*"}, []string{"*"}, false},
+		{"action from a module", []string{"user.*"}, []string{"*.view"}, false},
+		{"module from an action", []string{"*.view"}, []string{"user.*"}, false},
+		{"narrower action", []string{"*.view"}, []string{"cert.*.view"}, true},
+		{"narrower module", []string{"cert.*"}, []string{"cert.*.view"}, true},
+		{"wider than held", []string{"cert.*.view"}, []string{"cert.*"}, false},
+		{"prefix without dot", []string{"user.*"}, []string{"user*"}, false},
+		{"action from all", []string{"*"}, []string{"*.delete"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCodes(tt.held, tt.requested)
+			if tt.ok && err != nil {
+				t.Errorf("checkCodes(%q, %q) = %v, want ok", tt.held, tt.requested, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrCodeNotHeld) {
+				t.Errorf("checkCodes(%q, %q) = %v, want ErrCodeNotHeld", tt.held, tt.requested, err)
+			}
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	tests := []struct {
+		name        string
+		token, held []string
+		want        []string
+	}{
+		{"intersection", []string{"user.view", "role.view"}, []string{"user.view"}, []string{"user.view"}},
+		{"owner lost everything", []string{"user.view"}, nil, []string{}},
+		{"owner holds all", []string{"user.view", "role.*"}, []string{"*"}, []string{"user.view", "role.*"}},
+		{"token holds all", []string{"*"}, []string{"user.view", "role.*"}, []string{"user.view", "role.*"}},
+		{"owner pattern", []string{"user.view", "user.*", "role.view"}, []string{"user.*"}, []string{"user.view", "user.*"}},
+		{"owner narrowed", []string{"user.*"}, []string{"user.view"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Effective(tt.token, tt.held); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Effective(%q, %q) = %q, want %q", tt.token, tt.held, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	got := dedupe([]string{" user.view", "user.view ", "", "  ", "role.*", "user.view"})
+	if want := []string{"user.view", "role.*"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupe = %q, want %q", got, want)
+	}
+}
@@ -3,16 +3,32 @@ package app
 import (
 	"github.com/casbin/casbin/v2"
 	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/apitoken"
 	"github.com/hysp/hyadmin-api/internal/auditlog"
 	localauth "github.com/hysp/hyadmin-api/internal/auth"
+	"github.com/hysp/hyadmin-api/internal/blindindex"
 	"github.com/hysp/hyadmin-api/internal/feature"
 	"github.com/hysp/hyadmin-api/internal/health"
+	"github.com/hysp/hyadmin-api/internal/invitation"
+	"github.com/hysp/hyadmin-api/internal/ldapauth"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mail"
+	"github.com/hysp/hyadmin-api/internal/mfa"
+	"github.com/hysp/hyadmin-api/internal/oidc"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/passwordreset"
 	"github.com/hysp/hyadmin-api/internal/pbmodule"
 	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/privacy"
+	"github.com/hysp/hyadmin-api/internal/provisioning"
 	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/scim"
 	"github.com/hysp/hyadmin-api/internal/server"
+	"github.com/hysp/hyadmin-api/internal/serviceaccount"
+	"github.com/hysp/hyadmin-api/internal/session"
+	"github.com/hysp/hyadmin-api/internal/setting"
 	"github.com/hysp/hyadmin-api/internal/tenant"
-	coreauth "github.com/robert7528/hycore/auth"
+	"github.com/hysp/hyadmin-api/internal/userbulk"
 	"github.com/robert7528/hycore/casbinx"
 	"github.com/robert7528/hycore/config"
 	"github.com/robert7528/hycore/crypto"
@@ -37,24 +53,112 @@ func Run() error {
 				return crypto.New(cfg.Tink.Keyset)
 			},
 
-			// Casbin enforcer
+			// Blind indexes for searching encrypted PII
+			blindindex.NewFromEnv,
+
+			// Casbin enforcer; permMatch resolves wildcard permission patterns
 			func(db *gorm.DB) (*casbin.Enforcer, error) {
-				return casbinx.NewEnforcer(db, "configs/rbac_model.conf", "hyadmin_casbin_rules")
+				e, err := casbinx.NewEnforcer(db, "configs/rbac_model.conf", "hyadmin_casbin_rules")
+				if err != nil {
+					return nil, err
+				}
+				e.AddFunction("permMatch", permission.MatchFunc)
+				return e, nil
 			},
 
+			// Settings
+			setting.NewRepository,
+			setting.NewService,
+			setting.NewHandler,
+
+			// Session domain
+			session.NewRepository,
+			session.NewService,
+			session.NewHandler,
+
+			// Password policy
+			password.NewService,
+			password.NewHandler,
+
 			// AdminUser domain
 			adminuser.NewRepository,
 			adminuser.NewService,
 			adminuser.NewHandler,
 
+			// MFA domain
+			mfa.NewRepository,
+			mfa.NewService,
+			mfa.NewHandler,
+
+			// Login lockout
+			lockout.NewRepository,
+			lockout.NewService,
+			lockout.NewHandler,
+
+			// Outgoing mail
+			mail.NewService,
+
+			// Self-service password reset
+			passwordreset.NewRepository,
+			passwordreset.NewService,
+			passwordreset.NewHandler,
+			invitation.NewRepository,
+			invitation.NewService,
+			invitation.NewHandler,
+
+			// Bulk user import/export
+			userbulk.NewService,
+			userbulk.NewHandler,
+
+			// Data subject export and erasure
+			privacy.NewRepository,
+			privacy.NewService,
+			privacy.NewHandler,
+
+			// Personal access tokens
+			apitoken.NewRepository,
+			apitoken.NewService,
+			apitoken.NewHandler,
+
+			// Service accounts (client credentials)
+			serviceaccount.NewRepository,
+			serviceaccount.NewService,
+			serviceaccount.NewHandler,
+
 			// Auth domain
-			func(cfg *config.Config, userSvc *adminuser.Service) *localauth.LocalProvider {
-				return localauth.NewLocalProvider(userSvc, cfg.JWT.ExpiryHours)
+			func(cfg *config.Config, userSvc *adminuser.Service, guard *lockout.Service) *localauth.LocalProvider {
+				return localauth.NewLocalProvider(userSvc, guard, cfg.JWT.ExpiryHours)
 			},
-			func(cfg *config.Config, lp *localauth.LocalProvider) *coreauth.Service {
-				return coreauth.NewService(cfg, lp)
+			func(cfg *config.Config, sessions *session.Service, users *adminuser.Service, mfaSvc *mfa.Service, guard *lockout.Service, settings *setting.Service, accounts *serviceaccount.Service, roles *role.Service, audit *auditlog.Service, lp *localauth.LocalProvider, op *oidc.Authenticator, ldp *ldapauth.Provider) *localauth.Service {
+				return localauth.NewService(cfg, sessions, users, mfaSvc, guard, settings, accounts, roles, audit, lp, op, ldp)
 			},
-			coreauth.NewHandler,
+			localauth.NewHandler,
+
+			// External identity provisioning (JIT users, group-to-role mapping)
+			provisioning.NewRepository,
+			provisioning.NewService,
+			provisioning.NewHandler,
+
+			// OIDC login (per-tenant identity providers)
+			oidc.NewRepository,
+			func(cfg *config.Config, repo *oidc.Repository, provisioner *provisioning.Service, enc crypto.Encryptor) *oidc.Service {
+				return oidc.NewService(repo, provisioner, enc, cfg.JWT.ExpiryHours)
+			},
+			oidc.NewAuthenticator,
+			oidc.NewHandler,
+
+			// LDAP / Active Directory login
+			ldapauth.NewRepository,
+			ldapauth.NewService,
+			func(cfg *config.Config, svc *ldapauth.Service, provisioner *provisioning.Service, guard *lockout.Service) *ldapauth.Provider {
+				return ldapauth.NewProvider(svc, provisioner, guard, cfg.JWT.ExpiryHours)
+			},
+			ldapauth.NewHandler,
+
+			// SCIM 2.0 provisioning
+			scim.NewRepository,
+			scim.NewService,
+			scim.NewHandler,
 
 			// Feature domain
 			feature.NewRepository,
@@ -75,6 +179,7 @@ func Run() error {
 			role.NewRepository,
 			role.NewService,
 			role.NewHandler,
+			role.NewExpiryJob,
 
 			// Tenant domain
 			tenant.NewRepository,
@@ -82,6 +187,7 @@ func Run() error {
 			tenant.NewHandler,
 
 			// AuditLog
+			auditlog.NewService,
 			auditlog.NewHandler,
 
 			// Health
@@ -89,6 +195,7 @@ func Run() error {
 		),
 		fx.Invoke(server.RegisterRoutes),
 		fx.Invoke(server.Start),
+		fx.Invoke(role.StartExpiryJob),
 	)
 	app.Run()
 	return nil
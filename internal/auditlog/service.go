@@ -0,0 +1,21 @@
+package auditlog
+
+import (
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"gorm.io/gorm"
+)
+
+// Service records audit entries that AuditMiddleware cannot capture,
+// e.g. authentication events on public routes or background jobs.
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Record writes an entry; best-effort like AuditMiddleware, errors are ignored.
+func (s *Service) Record(entry *coreauditlog.AuditLog) {
+	s.db.Create(entry)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/robert7528/hycore/auth"
+
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+// ScopeMFAChallenge marks the short-lived token returned by /auth/login when a
+// second factor is still required. It is never accepted as an access token.
+const ScopeMFAChallenge = "mfa_challenge"
+
+// restrictedScopes lists the path prefixes an access token of a scoped session
+// may reach. A token carrying several scopes may reach the union of them.
+var restrictedScopes = map[string][]string{
+	session.ScopeMFAEnroll:      {"/api/v1/profile/mfa"},
+	session.ScopePasswordChange: {"/api/v1/profile/password"},
+}
+
+// Claims extends the hycore JWT payload with hyadmin-specific fields.
+type Claims struct {
+	coreauth.Claims
+	Scope string `json:"scope,omitempty"` // space-separated list, see restrictedScopes
+
+	// Set only for personal access tokens, which are not JWTs: the token ID
+	// and the permission codes the request is limited to.
+	TokenID uint     `json:"-"`
+	Codes   []string `json:"-"`
+
+	// ServiceAccountID is set (and UserID is 0) for client-credentials tokens.
+	ServiceAccountID uint `json:"sa,omitempty"`
+
+	// Impersonator is set when an administrator acts as UserID; writes are
+	// audited under the impersonator and the UI shows a banner.
+	Impersonator *Impersonator `json:"imp,omitempty"`
+}
+
+// Impersonator identifies the real actor behind an impersonation token.
+type Impersonator struct {
+	UserID     uint   `json:"user_id"`
+	TenantCode string `json:"tenant_code"`
+	Username   string `json:"username"`
+}
+
+// CasbinSubject is the policy subject of the principal behind the token.
+func (c *Claims) CasbinSubject() string {
+	if c.ServiceAccountID != 0 {
+		return role.ServiceAccountSubject(c.ServiceAccountID)
+	}
+	return role.UserSubject(c.UserID)
+}
+
+const hyClaimsKey = "hyadmin_claims"
+
+// GetClaims retrieves the extended claims set by AuthMiddleware.
+// Use hycore middleware.GetClaims when only the core fields are needed.
+func GetClaims(c *gin.Context) *Claims {
+	v, exists := c.Get(hyClaimsKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := v.(*Claims)
+	return claims
+}
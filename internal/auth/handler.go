@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+// Handler provides HTTP endpoints for authentication.
+type Handler struct {
+	svc *Service
+}
+
+// NewHandler creates an auth Handler.
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+type loginRequest struct {
+	Provider   string `json:"provider"`    // default "local"
+	TenantCode string `json:"tenant_code"` // required for local
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP or recovery code
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Login POST /api/v1/auth/login
+func (h *Handler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	creds := map[string]string{
+		"tenant_code": req.TenantCode,
+		"username":    req.Username,
+		"password":    req.Password,
+	}
+	provider := req.Provider
+	if provider == "" {
+		provider = "local"
+	}
+	res, err := h.svc.Login(c.Request.Context(), provider, creds, clientInfo(c))
+	if err != nil {
+		if !abortLocked(c, err) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		}
+		return
+	}
+	if res.MFAChallenge != "" {
+		c.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": res.MFAChallenge, "provider": provider})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":         res.Tokens.AccessToken,
+		"refresh_token": res.Tokens.RefreshToken,
+		"expires_in":    res.Tokens.ExpiresIn,
+		"token_type":    res.Tokens.TokenType,
+		"scope":         res.Tokens.Scope,
+		"provider":      provider,
+	})
+}
+
+// LoginMFA POST /api/v1/auth/login/mfa
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pair, err := h.svc.CompleteMFA(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		if !abortLocked(c, err) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa code"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, pair)
+}
+
+// Refresh POST /api/v1/auth/refresh
+func (h *Handler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pair, err := h.svc.Refresh(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, pair)
+}
+
+// Token POST /api/v1/auth/token
+// OAuth2 token endpoint for service accounts (grant_type=client_credentials).
+// Client credentials may be sent with HTTP Basic auth or in the form/JSON body.
+func (h *Handler) Token(c *gin.Context) {
+	var req struct {
+		GrantType    string `form:"grant_type" json:"grant_type"`
+		ClientID     string `form:"client_id" json:"client_id"`
+		ClientSecret string `form:"client_secret" json:"client_secret"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	if req.GrantType != "client_credentials" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	pair, err := h.svc.ClientCredentials(req.ClientID, req.ClientSecret)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Basic realm="hyadmin"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": pair.AccessToken,
+		"token_type":   pair.TokenType,
+		"expires_in":   pair.ExpiresIn,
+	})
+}
+
+// Impersonate POST /api/v1/admin/users/:id/impersonate
+// Returns a short-lived access token acting as the user. The response (and
+// /permissions/me while the token is used) carries the impersonation banner data.
+func (h *Handler) Impersonate(c *gin.Context) {
+	actor := GetClaims(c)
+	if actor == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	pair, err := h.svc.Impersonate(actor, uint(id), clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, ErrCannotImpersonate), errors.Is(err, ErrUserDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":         pair.AccessToken,
+		"expires_in":    pair.ExpiresIn,
+		"token_type":    pair.TokenType,
+		"impersonation": true,
+		"impersonator": Impersonator{
+			UserID:     actor.UserID,
+			TenantCode: actor.TenantCode,
+			Username:   actor.Username,
+		},
+	})
+}
+
+// Logout POST /api/v1/auth/logout
+// Revokes the session identified by the refresh token in the body and/or the
+// Bearer access token; always succeeds so clients can clear local state.
+func (h *Handler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	access := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if access == c.GetHeader("Authorization") {
+		access = ""
+	}
+	h.svc.Logout(req.RefreshToken, access)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// abortLocked answers 429 with Retry-After when err is a lockout.
+// The same response is given for existing and unknown usernames.
+func abortLocked(c *gin.Context, err error) bool {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", fmt.Sprintf("%d", int(locked.RetryAfter.Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts"})
+	return true
+}
+
+func clientInfo(c *gin.Context) session.ClientInfo {
+	return session.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
@@ -2,26 +2,35 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	coreauth "github.com/robert7528/hycore/auth"
 
 	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/lockout"
 )
 
+// ErrInvalidCredentials is the only error LocalProvider reports for a bad
+// login, whatever the cause, so responses cannot be used to enumerate users.
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
 // LocalProvider authenticates users against the local admin_users table.
 type LocalProvider struct {
 	userSvc *adminuser.Service
+	guard   *lockout.Service
 	expiry  int
 }
 
 // NewLocalProvider creates a LocalProvider.
-func NewLocalProvider(userSvc *adminuser.Service, expiryHours int) *LocalProvider {
-	return &LocalProvider{userSvc: userSvc, expiry: expiryHours}
+func NewLocalProvider(userSvc *adminuser.Service, guard *lockout.Service, expiryHours int) *LocalProvider {
+	return &LocalProvider{userSvc: userSvc, guard: guard, expiry: expiryHours}
 }
 
 func (p *LocalProvider) Name() string { return "local" }
 
+// Authenticate expects tenant_code, username and password in creds; client_ip
+// is filled in by Service.Login and keys the per-IP throttle.
 func (p *LocalProvider) Authenticate(_ context.Context, creds map[string]string) (*coreauth.Claims, error) {
 	tenantCode := creds["tenant_code"]
 	username := creds["username"]
@@ -31,10 +40,31 @@ func (p *LocalProvider) Authenticate(_ context.Context, creds map[string]string)
 		return nil, fmt.Errorf("auth: tenant_code and username are required")
 	}
 
+	attempt := lockout.Attempt{TenantCode: tenantCode, Username: username, IP: creds["client_ip"]}
+	if err := p.guard.Check(attempt); err != nil {
+		return nil, err
+	}
+
 	u, err := p.userSvc.VerifyPassword(tenantCode, username, password)
+	if u != nil {
+		attempt.UserID = u.ID
+	}
 	if err != nil {
-		return nil, err
+		p.guard.Fail(attempt, failureReason(err))
+		return nil, ErrInvalidCredentials
 	}
+	p.guard.Succeed(attempt)
 
 	return coreauth.NewClaims(u.ID, u.TenantCode, u.Username, "local", p.expiry), nil
 }
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, adminuser.ErrUserNotFound):
+		return "user_not_found"
+	case errors.Is(err, adminuser.ErrUserDisabled):
+		return "user_disabled"
+	default:
+		return "invalid_password"
+	}
+}
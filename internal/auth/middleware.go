@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/robert7528/hycore/auth"
+
+	"github.com/hysp/hyadmin-api/internal/apitoken"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+// claimsKey mirrors the context key of hycore/middleware so that
+// middleware.GetClaims keeps working for handlers and the audit middleware.
+const claimsKey = "auth_claims"
+
+// AuthMiddleware validates Bearer access tokens and rejects tokens whose
+// session (jti) has been revoked or has expired server-side.
+// Scoped tokens may only reach the paths listed in restrictedScopes.
+// Bearer values starting with apitoken.Prefix are personal access tokens.
+func AuthMiddleware(svc *Service, sessions *session.Service, tokens *apitoken.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" || !strings.HasPrefix(header, "Bearer ") {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+			c.Abort()
+			return
+		}
+		raw := strings.TrimPrefix(header, "Bearer ")
+		if strings.HasPrefix(raw, apitoken.Prefix) {
+			authenticateToken(c, tokens, raw)
+			return
+		}
+		claims, err := svc.ParseToken(raw)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.Abort()
+			return
+		}
+		if claims.ServiceAccountID != 0 {
+			// Client-credentials tokens have no session; they die with the account.
+			if !svc.ServiceAccountActive(claims.ServiceAccountID) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "service account disabled"})
+				c.Abort()
+				return
+			}
+			if strings.HasPrefix(c.Request.URL.Path, "/api/v1/profile") {
+				c.JSON(http.StatusForbidden, gin.H{"error": "service accounts have no profile"})
+				c.Abort()
+				return
+			}
+		} else if !sessions.IsActive(claims.ID, claims.UserID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			c.Abort()
+			return
+		}
+		if claims.Impersonator != nil && c.Request.Method != http.MethodGet &&
+			strings.HasPrefix(c.Request.URL.Path, "/api/v1/profile") {
+			// An impersonator must not change the user's credentials, MFA or tokens.
+			c.JSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+			c.Abort()
+			return
+		}
+		if claims.Scope != "" && !scopeAllows(claims.Scope, c.Request.URL.Path) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "token scope does not allow this request", "scope": claims.Scope})
+			c.Abort()
+			return
+		}
+		c.Set(claimsKey, &claims.Claims)
+		c.Set(hyClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// authenticateToken handles personal access tokens. They cannot reach
+// /profile, so a leaked token cannot mint more tokens or change credentials.
+func authenticateToken(c *gin.Context, tokens *apitoken.Service, raw string) {
+	p, err := tokens.Verify(raw, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		c.Abort()
+		return
+	}
+	if strings.HasPrefix(c.Request.URL.Path, "/api/v1/profile") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "personal access tokens cannot access profile endpoints"})
+		c.Abort()
+		return
+	}
+	claims := &Claims{
+		Claims: coreauth.Claims{
+			UserID:     p.UserID,
+			TenantCode: p.TenantCode,
+			Username:   p.Username,
+			Provider:   p.Provider,
+		},
+		TokenID: p.TokenID,
+		Codes:   p.Codes,
+	}
+	claims.ID = fmt.Sprintf("pat:%d", p.TokenID)
+	c.Set(claimsKey, &claims.Claims)
+	c.Set(hyClaimsKey, claims)
+	c.Next()
+}
+
+func scopeAllows(scope, path string) bool {
+	for _, sc := range strings.Fields(scope) {
+		for _, prefix := range restrictedScopes[sc] {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
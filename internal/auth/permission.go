@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/robert7528/hycore/middleware"
+
+	"github.com/hysp/hyadmin-api/internal/permission"
+)
+
+// deniedCodesKey holds the codes denied to the caller; see GetDeniedCodes.
+const deniedCodesKey = "denied_permission_codes"
+
+// PermissionLoader resolves permission codes for users and service accounts
+// within a tenant (the Casbin domain). Allowed codes already exclude denied
+// ones; the denied codes are needed to override wildcard allows such as "*".
+type PermissionLoader interface {
+	GetPermissionCodesForUser(tenantCode string, userID uint) ([]string, error)
+	GetPermissionCodesForServiceAccount(tenantCode string, id uint) ([]string, error)
+	GetDeniedCodesForUser(tenantCode string, userID uint) ([]string, error)
+	GetDeniedCodesForServiceAccount(tenantCode string, id uint) ([]string, error)
+}
+
+// PermissionLoaderMiddleware resolves permission codes like hycore's loader,
+// except that personal access tokens get only the codes they carry and
+// service accounts are resolved through their own subject. Personal access
+// tokens are still subject to their owner's denies.
+// Must run after AuthMiddleware.
+func PermissionLoaderMiddleware(loader PermissionLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := GetClaims(c); claims != nil {
+			var codes, denied []string
+			var err, derr error
+			switch {
+			case claims.TokenID != 0:
+				codes = claims.Codes
+				denied, derr = loader.GetDeniedCodesForUser(claims.TenantCode, claims.UserID)
+			case claims.ServiceAccountID != 0:
+				codes, err = loader.GetPermissionCodesForServiceAccount(claims.TenantCode, claims.ServiceAccountID)
+				denied, derr = loader.GetDeniedCodesForServiceAccount(claims.TenantCode, claims.ServiceAccountID)
+			default:
+				codes, err = loader.GetPermissionCodesForUser(claims.TenantCode, claims.UserID)
+				denied, derr = loader.GetDeniedCodesForUser(claims.TenantCode, claims.UserID)
+			}
+			if err == nil && derr == nil {
+				middleware.SetPermissionCodes(c, codes)
+				c.Set(deniedCodesKey, denied)
+			}
+		}
+		c.Next()
+	}
+}
+
+// GetDeniedCodes returns the codes denied to the caller by deny policies.
+func GetDeniedCodes(c *gin.Context) []string {
+	if v, ok := c.Get(deniedCodesKey); ok {
+		if codes, ok := v.([]string); ok {
+			return codes
+		}
+	}
+	return nil
+}
+
+// PermissionMiddleware checks the X-Permission header against Casbin, like
+// hycore's but within the caller's tenant domain. Personal access tokens must
+// always name the permission, and it must be one of the token's codes.
+func PermissionMiddleware(enforcer *casbin.Enforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		permCode := c.GetHeader("X-Permission")
+		if claims != nil && claims.TokenID != 0 && !hasCode(claims.Codes, permCode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
+		}
+		if permCode != "" && claims != nil {
+			if ok, _ := enforcer.Enforce(claims.CasbinSubject(), claims.TenantCode, permCode, "access"); !ok {
+				c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission aborts with 403 unless the loaded permission codes
+// include code and it is not denied. Use it for endpoints whose permission
+// the caller cannot choose through X-Permission. Must run after
+// PermissionLoaderMiddleware.
+func RequirePermission(code string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasCode(middleware.GetPermissionCodes(c), code) || isDenied(GetDeniedCodes(c), code) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isDenied(denied []string, code string) bool {
+	return permission.MatchAny(denied, code)
+}
+
+// hasCode reports whether code is covered by codes, which may be patterns
+// such as "*" or "cert.*".
+func hasCode(codes []string, code string) bool {
+	if code == "" {
+		return false
+	}
+	return permission.MatchAny(codes, code)
+}
+
+// covers reports whether a principal allowed allow and denied deny holds
+// every one of codes, so acting as their holder grants nothing new.
+func covers(allow, deny, codes []string) bool {
+	for _, code := range codes {
+		if !hasCode(allow, code) || isDenied(deny, code) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "testing"
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name        string
+		allow, deny []string
+		target      []string
+		want        bool
+	}{
+		{"target holds nothing", []string{"user.view"}, nil, nil, true},
+		{"subset", []string{"user.view", "user.edit"}, nil, []string{"user.view"}, true},
+		{"missing code", []string{"user.view"}, nil, []string{"user.view", "user.delete"}, false},
+		{"pattern allow", []string{"user.*"}, nil, []string{"user.view", "user.delete"}, true},
+		{"all", []string{"*"}, nil, []string{"user.delete", "cert.view"}, true},
+		{"all but denied code", []string{"*"}, []string{"user.delete"}, []string{"user.view", "user.delete"}, false},
+		{"all but denied pattern", []string{"*"}, []string{"cert.*"}, []string{"cert.view"}, false},
+		{"denied code target lacks", []string{"*"}, []string{"user.delete"}, []string{"user.view"}, true},
+		{"pattern allow, denied code", []string{"user.*"}, []string{"user.delete"}, []string{"user.delete"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := covers(tt.allow, tt.deny, tt.target); got != tt.want {
+				t.Errorf("covers(%q, %q, %q) = %v, want %v", tt.allow, tt.deny, tt.target, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	coreauth "github.com/robert7528/hycore/auth"
+	"github.com/robert7528/hycore/config"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mfa"
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/serviceaccount"
+	"github.com/hysp/hyadmin-api/internal/session"
+	"github.com/hysp/hyadmin-api/internal/setting"
+	"github.com/hysp/hyadmin-api/internal/tenant"
+)
+
+const (
+	defaultAccessTokenMinutes = 15
+	defaultSessionHours       = 24
+	mfaChallengeTTL           = 5 * time.Minute
+)
+
+var (
+	ErrUserDisabled        = errors.New("auth: user disabled")
+	ErrInvalidMFAChallenge = errors.New("auth: invalid mfa challenge")
+	ErrCannotImpersonate   = errors.New("auth: cannot impersonate this user")
+)
+
+// TokenPair is returned by login and refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// LoginResult carries either a token pair or, when a second factor is
+// still required, an MFA challenge token to be completed at /auth/login/mfa.
+type LoginResult struct {
+	Tokens       *TokenPair
+	MFAChallenge string
+}
+
+// Service authenticates through the registered providers and issues
+// short-lived access tokens bound to a server-side session.
+type Service struct {
+	providers map[string]coreauth.Provider
+	sessions  *session.Service
+	users     *adminuser.Service
+	mfa       *mfa.Service
+	guard     *lockout.Service
+	settings  *setting.Service
+	accounts  *serviceaccount.Service
+	roles     *role.Service
+	audit     *auditlog.Service
+	secret    []byte
+}
+
+// NewService constructs an auth Service with one or more providers.
+func NewService(cfg *config.Config, sessions *session.Service, users *adminuser.Service, mfaSvc *mfa.Service, guard *lockout.Service, settings *setting.Service, accounts *serviceaccount.Service, roles *role.Service, audit *auditlog.Service, providers ...coreauth.Provider) *Service {
+	m := make(map[string]coreauth.Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &Service{
+		providers: m,
+		sessions:  sessions,
+		users:     users,
+		mfa:       mfaSvc,
+		guard:     guard,
+		settings:  settings,
+		accounts:  accounts,
+		roles:     roles,
+		audit:     audit,
+		secret:    []byte(cfg.JWT.Secret),
+	}
+}
+
+// Login authenticates via the named provider. Password users (see
+// passwordProvider) with TOTP enabled get an MFA challenge instead of tokens;
+// users with pending obligations (see pendingScopes) get a restricted session.
+func (s *Service) Login(ctx context.Context, providerName string, creds map[string]string, info session.ClientInfo) (*LoginResult, error) {
+	if providerName == "" {
+		providerName = "local"
+	}
+	p, ok := s.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown provider %q", providerName)
+	}
+	creds["client_ip"] = info.IP
+	core, err := p.Authenticate(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{Claims: *core}
+
+	if passwordProvider(claims.Provider) && s.mfa.Enabled(claims.UserID) {
+		challenge, err := s.challenge(claims)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAChallenge: challenge}, nil
+	}
+	tokens, err := s.openSession(claims, info)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// CompleteMFA verifies the second factor for an MFA challenge and opens the session.
+// Wrong codes count towards the same lockout as wrong passwords.
+func (s *Service) CompleteMFA(challenge, code string, info session.ClientInfo) (*TokenPair, error) {
+	claims, err := s.ParseToken(challenge)
+	if err != nil || claims.Scope != ScopeMFAChallenge {
+		return nil, ErrInvalidMFAChallenge
+	}
+	attempt := lockout.Attempt{TenantCode: claims.TenantCode, Username: claims.Username, IP: info.IP, UserID: claims.UserID}
+	if err := s.guard.Check(attempt); err != nil {
+		return nil, err
+	}
+	if err := s.mfa.Verify(claims.UserID, code); err != nil {
+		s.guard.Fail(attempt, "invalid_mfa_code")
+		return nil, err
+	}
+	s.guard.Succeed(attempt)
+	return s.openSession(claims, info)
+}
+
+// Refresh rotates the refresh token and issues a new access token for the same session.
+func (s *Service) Refresh(refreshToken string, info session.ClientInfo) (*TokenPair, error) {
+	sess, refresh, err := s.sessions.Rotate(refreshToken, info)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetByID(sess.UserID)
+	if err != nil || !u.Enabled {
+		_ = s.sessions.Revoke(sess.ID, "user_disabled")
+		return nil, ErrUserDisabled
+	}
+	claims := &Claims{
+		Claims: *coreauth.NewClaims(sess.UserID, sess.TenantCode, sess.Username, sess.Provider, 0),
+		Scope:  sess.Scope,
+	}
+	return s.issue(claims, sess.ID, refresh)
+}
+
+// ClientCredentials implements the OAuth2 client-credentials grant for
+// service accounts: a short-lived, session-less access token, no refresh token.
+func (s *Service) ClientCredentials(clientID, clientSecret string) (*TokenPair, error) {
+	a, err := s.accounts.Authenticate(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{
+		Claims:           *coreauth.NewClaims(0, a.TenantCode, "svc:"+a.Name, "service_account", 0),
+		ServiceAccountID: a.ID,
+	}
+	ttl := time.Duration(s.settings.GetTenantInt(a.TenantCode, "auth.service_account.token_expire_minutes", defaultAccessTokenMinutes)) * time.Minute
+	signed, err := s.sign(claims, "", ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: signed, ExpiresIn: int(ttl.Seconds()), TokenType: "Bearer"}, nil
+}
+
+// Impersonate issues a time-limited, non-refreshable token that acts as
+// targetID on behalf of actor. Impersonation cannot be chained, and the
+// target may not hold permissions the actor lacks or is denied (see covers),
+// so it never escalates privileges. Only platform admins (see
+// tenant.IsPlatformAdmin) may impersonate users of other tenants.
+func (s *Service) Impersonate(actor *Claims, targetID uint, info session.ClientInfo) (*TokenPair, error) {
+	if actor.Impersonator != nil || actor.ServiceAccountID != 0 || actor.TokenID != 0 || actor.UserID == targetID {
+		return nil, ErrCannotImpersonate
+	}
+	target, err := s.users.GetUser(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !target.Enabled {
+		return nil, ErrUserDisabled
+	}
+	actorCodes, err := s.roles.GetPermissionCodesForUser(actor.TenantCode, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if target.TenantCode != actor.TenantCode && !tenant.IsPlatformAdmin(actor.TenantCode, actorCodes) {
+		return nil, ErrCannotImpersonate
+	}
+	actorDenied, err := s.roles.GetDeniedCodesForUser(actor.TenantCode, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCode(actorCodes, "*") || len(actorDenied) > 0 {
+		targetCodes, err := s.roles.EffectiveCodesForUser(target.TenantCode, target.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !covers(actorCodes, actorDenied, targetCodes) {
+			return nil, ErrCannotImpersonate
+		}
+	}
+
+	claims := &Claims{
+		Claims: *coreauth.NewClaims(target.ID, target.TenantCode, target.Username, target.Provider, 0),
+		Impersonator: &Impersonator{
+			UserID:     actor.UserID,
+			TenantCode: actor.TenantCode,
+			Username:   actor.Username,
+		},
+	}
+	ttl := time.Duration(s.settings.GetInt("auth.impersonation.expire_minutes", 30)) * time.Minute
+	sess, err := s.sessions.CreateImpersonation(&claims.Claims, actor.UserID, info, ttl)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := s.sign(claims, sess.ID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, _ := json.Marshal(map[string]interface{}{
+		"impersonated_user_id":     target.ID,
+		"impersonated_tenant_code": target.TenantCode,
+		"impersonated_username":    target.Username,
+		"session_id":               sess.ID,
+		"expires_in":               int(ttl.Seconds()),
+	})
+	s.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: actor.TenantCode,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		Action:     "IMPERSONATE",
+		Resource:   "users",
+		ResourceID: fmt.Sprintf("%d", target.ID),
+		Detail:     string(detail),
+		IP:         info.IP,
+		UserAgent:  info.UserAgent,
+	})
+	return &TokenPair{AccessToken: signed, ExpiresIn: int(ttl.Seconds()), TokenType: "Bearer"}, nil
+}
+
+// ServiceAccountActive reports whether a service account's tokens are still honoured.
+func (s *Service) ServiceAccountActive(id uint) bool {
+	return s.accounts.IsActive(id)
+}
+
+// Logout revokes the session behind a refresh token or an access token.
+// Either may be empty; unknown tokens are ignored.
+func (s *Service) Logout(refreshToken, accessToken string) {
+	if refreshToken != "" {
+		_ = s.sessions.RevokeByRefreshToken(refreshToken, "logout")
+	}
+	if accessToken != "" {
+		if claims, err := s.ParseToken(accessToken); err == nil && claims.ID != "" {
+			_ = s.sessions.Revoke(claims.ID, "logout")
+		}
+	}
+}
+
+// ParseToken validates a token signed by this service and returns its claims.
+func (s *Service) ParseToken(tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("auth: unexpected signing method %v", t.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid token: %w", err)
+	}
+	c, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("auth: invalid claims")
+	}
+	return c, nil
+}
+
+func (s *Service) openSession(claims *Claims, info session.ClientInfo) (*TokenPair, error) {
+	claims.Scope = s.pendingScopes(claims)
+	ttl := time.Duration(s.settings.GetInt("auth.session.expire_hours", defaultSessionHours)) * time.Hour
+	sess, refresh, err := s.sessions.Create(&claims.Claims, claims.Scope, info, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(claims, sess.ID, refresh)
+}
+
+// passwordProvider reports whether provider logs users in with a password
+// checked by this service, so the tenant's MFA policy applies to it. OIDC
+// users are exempt: the identity provider owns their second factor.
+func passwordProvider(provider string) bool {
+	return provider == "local" || provider == "ldap"
+}
+
+// pendingScopes lists what a password user must do before getting full access:
+// change an expired or admin-set password, and/or enroll in MFA if the tenant
+// requires it. Password changes only apply to local users; LDAP passwords are
+// managed by the directory.
+func (s *Service) pendingScopes(claims *Claims) string {
+	if !passwordProvider(claims.Provider) {
+		return ""
+	}
+	var scopes []string
+	if claims.Provider == "local" {
+		if required, err := s.users.PasswordChangeRequired(claims.UserID); err == nil && required {
+			scopes = append(scopes, session.ScopePasswordChange)
+		}
+	}
+	if s.mfa.Required(claims.TenantCode) && !s.mfa.Enabled(claims.UserID) {
+		scopes = append(scopes, session.ScopeMFAEnroll)
+	}
+	return strings.Join(scopes, " ")
+}
+
+func (s *Service) issue(claims *Claims, sessionID, refresh string) (*TokenPair, error) {
+	ttl := time.Duration(s.settings.GetInt("auth.access_token.expire_minutes", defaultAccessTokenMinutes)) * time.Minute
+	signed, err := s.sign(claims, sessionID, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  signed,
+		RefreshToken: refresh,
+		ExpiresIn:    int(ttl.Seconds()),
+		TokenType:    "Bearer",
+		Scope:        claims.Scope,
+	}, nil
+}
+
+// challenge signs a session-less token that only /auth/login/mfa accepts.
+func (s *Service) challenge(claims *Claims) (string, error) {
+	c := *claims
+	c.Scope = ScopeMFAChallenge
+	return s.sign(&c, "", mfaChallengeTTL)
+}
+
+func (s *Service) sign(claims *Claims, jti string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.ID = jti
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("auth: sign token: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,154 @@
+// Package blindindex computes blind indexes: keyed HMAC-SHA256 digests of
+// normalized PII that support equality and token search over encrypted
+// columns without storing plaintext.
+package blindindex
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/robert7528/hycore/config"
+	"go.uber.org/zap"
+)
+
+// KeyEnv holds the base64-encoded HMAC key (at least 32 bytes). Changing the
+// key requires `hyadmin users reindex`.
+const KeyEnv = "BLIND_INDEX_KEY"
+
+const (
+	minPrefix = 3  // shortest indexed prefix of a word
+	maxWord   = 32 // longer words are indexed by their first maxWord runes
+	termHex   = 16 // search terms are truncated to 64 bits to blur frequency analysis
+)
+
+type Indexer struct {
+	key []byte
+}
+
+func New(key []byte) *Indexer {
+	return &Indexer{key: key}
+}
+
+// NewFromEnv reads the key from BLIND_INDEX_KEY. Without it the key is derived
+// from the JWT secret, which ties the index to that secret; a warning is logged.
+func NewFromEnv(cfg *config.Config, log *zap.Logger) (*Indexer, error) {
+	if v := os.Getenv(KeyEnv); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("blindindex: decode %s: %w", KeyEnv, err)
+		}
+		if len(key) < 32 {
+			return nil, fmt.Errorf("blindindex: %s must be at least 32 bytes", KeyEnv)
+		}
+		return New(key), nil
+	}
+	log.Warn("blind index key not set; deriving it from the JWT secret", zap.String("env", KeyEnv))
+	mac := hmac.New(sha256.New, []byte(cfg.JWT.Secret))
+	mac.Write([]byte("hyadmin blind index"))
+	return New(mac.Sum(nil)), nil
+}
+
+// Email returns the exact-match index of an email address, or "" for none.
+func (ix *Indexer) Email(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	return ix.digest("e:" + email)
+}
+
+// Terms returns the space-separated search terms stored for the given texts:
+// every word and its prefixes of at least three characters, and for CJK
+// text every character and character pair.
+func (ix *Indexer) Terms(texts ...string) string {
+	set := make(map[string]struct{})
+	for _, text := range texts {
+		for _, word := range words(text) {
+			r := []rune(word)
+			if len(r) > maxWord {
+				r = r[:maxWord]
+			}
+			if isCJK(r) {
+				for i := range r {
+					set[string(r[i])] = struct{}{}
+					if i+1 < len(r) {
+						set[string(r[i:i+2])] = struct{}{}
+					}
+				}
+				continue
+			}
+			set[string(r)] = struct{}{}
+			for n := minPrefix; n < len(r); n++ {
+				set[string(r[:n])] = struct{}{}
+			}
+		}
+	}
+	terms := make([]string, 0, len(set))
+	for t := range set {
+		terms = append(terms, ix.term(t))
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, " ")
+}
+
+// Query splits a search string into words and returns, per word, the words
+// itself (for matching plaintext columns) and the terms that must all be
+// present in a stored Terms value for the word to match.
+func (ix *Indexer) Query(q string) []Word {
+	var out []Word
+	for _, word := range words(q) {
+		r := []rune(word)
+		if len(r) > maxWord {
+			r = r[:maxWord]
+		}
+		w := Word{Text: word}
+		if isCJK(r) && len(r) > 1 {
+			for i := 0; i+1 < len(r); i++ {
+				w.Terms = append(w.Terms, ix.term(string(r[i:i+2])))
+			}
+		} else {
+			w.Terms = []string{ix.term(string(r))}
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// Word is one word of a search query, see Query.
+type Word struct {
+	Text  string
+	Terms []string
+}
+
+func (ix *Indexer) term(t string) string {
+	return ix.digest("t:" + t)[:termHex]
+}
+
+func (ix *Indexer) digest(s string) string {
+	mac := hmac.New(sha256.New, ix.key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// words lower-cases text and splits it on anything that is not a letter or digit.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isCJK(r []rune) bool {
+	for _, c := range r {
+		if unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
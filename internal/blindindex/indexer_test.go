@@ -0,0 +1,151 @@
+package blindindex
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// matches reports whether a search for q finds a row whose stored Terms
+// value is stored: every word of q must have all of its terms present.
+func matches(ix *Indexer, stored, q string) bool {
+	have := strings.Fields(stored)
+	words := ix.Query(q)
+	if len(words) == 0 {
+		return false
+	}
+	for _, w := range words {
+		for _, t := range w.Terms {
+			if !slices.Contains(have, t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestSearch(t *testing.T) {
+	ix := New(testKey)
+	tests := []struct {
+		name  string
+		texts []string
+		q     string
+		want  bool
+	}{
+		{"whole word", []string{"Alice Example"}, "alice", true},
+		{"case insensitive", []string{"Alice Example"}, "ALICE", true},
+		{"prefix", []string{"Alice Example"}, "exa", true},
+		{"prefix too short", []string{"Alice Example"}, "ex", false},
+		{"infix", []string{"Alice Example"}, "xamp", false},
+		{"longer than the word", []string{"Alice"}, "alices", false},
+		{"every word", []string{"Alice Example"}, "ali exam", true},
+		{"one word missing", []string{"Alice Example"}, "alice smith", false},
+		{"across texts", []string{"Alice", "alice@example.com"}, "alice example", true},
+		{"email split on punctuation", []string{"alice.smith@example.com"}, "smith", true},
+		{"digits", []string{"agent 007"}, "007", true},
+		{"long word truncated", []string{strings.Repeat("x", 40)}, strings.Repeat("x", 36), true},
+		{"cjk character", []string{"王小明"}, "小", true},
+		{"cjk pair", []string{"王小明"}, "小明", true},
+		{"cjk full name", []string{"王小明"}, "王小明", true},
+		{"cjk pair out of order", []string{"王小明"}, "明小", false},
+		{"cjk other name", []string{"王小明"}, "李", false},
+		{"empty query", []string{"Alice"}, "", false},
+		{"punctuation only", []string{"Alice"}, "@.-", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := ix.Terms(tt.texts...)
+			if got := matches(ix, stored, tt.q); got != tt.want {
+				t.Errorf("search %q in %q = %v, want %v", tt.q, tt.texts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	ix := New(testKey)
+	tests := []struct {
+		text string
+		want int // distinct terms
+	}{
+		{"", 0},
+		{"ab", 1},    // too short for prefixes: the word only
+		{"abc", 1},   // the word is its only prefix
+		{"alice", 3}, // ali, alic, alice
+		{"alice alice", 3},
+		{"王小明", 5}, // 3 characters and 2 pairs
+		{strings.Repeat("x", 40), maxWord - minPrefix + 1},
+	}
+	for _, tt := range tests {
+		got := ix.Terms(tt.text)
+		terms := strings.Fields(got)
+		if len(terms) != tt.want {
+			t.Errorf("Terms(%q) has %d terms, want %d", tt.text, len(terms), tt.want)
+		}
+		if !slices.IsSorted(terms) {
+			t.Errorf("Terms(%q) is not sorted", tt.text)
+		}
+		for _, term := range terms {
+			if len(term) != termHex {
+				t.Errorf("Terms(%q): term %q is %d characters, want %d", tt.text, term, len(term), termHex)
+			}
+		}
+		if strings.Contains(got, "alice") || strings.Contains(got, "小") {
+			t.Errorf("Terms(%q) = %q leaks plaintext", tt.text, got)
+		}
+	}
+}
+
+func TestQueryWords(t *testing.T) {
+	ix := New(testKey)
+	got := ix.Query("  Alice, 王小明 ")
+	if len(got) != 2 {
+		t.Fatalf("Query returned %d words, want 2", len(got))
+	}
+	if got[0].Text != "alice" || len(got[0].Terms) != 1 {
+		t.Errorf("word 0 = %q with %d terms, want alice with 1", got[0].Text, len(got[0].Terms))
+	}
+	if got[1].Text != "王小明" || len(got[1].Terms) != 2 {
+		t.Errorf("word 1 = %q with %d terms, want 王小明 with 2", got[1].Text, len(got[1].Terms))
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	a, b := New(testKey), New([]byte("another key of at least 32 bytes"))
+	if a.Terms("alice") == b.Terms("alice") {
+		t.Error("Terms does not depend on the key")
+	}
+	if a.Email("alice@example.com") == b.Email("alice@example.com") {
+		t.Error("Email does not depend on the key")
+	}
+}
+
+func TestEmail(t *testing.T) {
+	ix := New(testKey)
+	alice := ix.Email("alice@example.com")
+	tests := []struct {
+		in   string
+		same bool // same index as alice@example.com
+	}{
+		{"alice@example.com", true},
+		{" Alice@Example.COM ", true},
+		{"alice@example.org", false},
+		{"alice.example.com", false},
+	}
+	for _, tt := range tests {
+		got := ix.Email(tt.in)
+		if got == "" || (got == alice) != tt.same {
+			t.Errorf("Email(%q) = %q; same as alice@example.com: %v, want %v", tt.in, got, got == alice, tt.same)
+		}
+	}
+	for _, in := range []string{"", "   "} {
+		if got := ix.Email(in); got != "" {
+			t.Errorf("Email(%q) = %q, want none", in, got)
+		}
+	}
+	if ix.Email("alice") == ix.Terms("alice") {
+		t.Error("email index and search terms share a namespace")
+	}
+}
@@ -13,12 +13,25 @@ import (
 
 	"ariga.io/atlas-provider-gorm/gormschema"
 	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/apitoken"
 	coreauditlog "github.com/robert7528/hycore/auditlog"
 	"github.com/robert7528/hycore/database"
 	"github.com/hysp/hyadmin-api/internal/feature"
+	"github.com/hysp/hyadmin-api/internal/invitation"
+	"github.com/hysp/hyadmin-api/internal/keyrotation"
+	"github.com/hysp/hyadmin-api/internal/ldapauth"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mfa"
+	"github.com/hysp/hyadmin-api/internal/oidc"
+	"github.com/hysp/hyadmin-api/internal/passwordreset"
 	"github.com/hysp/hyadmin-api/internal/pbmodule"
 	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/provisioning"
 	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/scim"
+	"github.com/hysp/hyadmin-api/internal/serviceaccount"
+	"github.com/hysp/hyadmin-api/internal/session"
+	"github.com/hysp/hyadmin-api/internal/setting"
 	"github.com/hysp/hyadmin-api/internal/tenant"
 )
 
@@ -35,6 +48,24 @@ func main() {
 		&permission.Permission{},
 		&permission.RolePermission{},
 		&coreauditlog.AuditLog{},
+		&setting.Setting{},
+		&setting.TenantSetting{},
+		&session.Session{},
+		&session.RotatedToken{},
+		&mfa.RecoveryCode{},
+		&lockout.Throttle{},
+		&adminuser.PasswordHistory{},
+		&passwordreset.Token{},
+		&oidc.ProviderConfig{},
+		&oidc.LoginState{},
+		&provisioning.RoleMapping{},
+		&provisioning.MappedRole{},
+		&ldapauth.Config{},
+		&apitoken.Token{},
+		&serviceaccount.ServiceAccount{},
+		&scim.Token{},
+		&invitation.Invitation{},
+		&keyrotation.Progress{},
 	)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load gorm schema: %v\n", err)
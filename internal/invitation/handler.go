@@ -0,0 +1,174 @@
+package invitation
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robert7528/hycore/middleware"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/tenant"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// List GET /api/v1/admin/invitations?tenant_code=&state=pending|accepted|expired|revoked
+// tenant_code defaults to the caller's tenant.
+func (h *Handler) List(c *gin.Context) {
+	tenantCode, ok := tenant.CallerCode(c, c.Query("tenant_code"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	list, err := h.svc.List(tenantCode, c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
+
+// Create POST /api/v1/admin/invitations
+// Creates a local user without a password and emails them an invitation link.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ok bool
+	if req.TenantCode, ok = tenant.CallerCode(c, req.TenantCode); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	inv, err := h.svc.Create(c.Request.Context(), &req, actorID(c))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inv)
+}
+
+// InviteUser POST /api/v1/admin/users/:id/invitation
+// Invites an existing local user who has no password yet. The route runs
+// behind adminuser.Handler.RequireTenantAccess.
+func (h *Handler) InviteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	inv, err := h.svc.Invite(c.Request.Context(), uint(id), actorID(c))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inv)
+}
+
+// Resend POST /api/v1/admin/invitations/:id/resend
+func (h *Handler) Resend(c *gin.Context) {
+	inv, ok := h.load(c)
+	if !ok {
+		return
+	}
+	inv, err := h.svc.Resend(c.Request.Context(), inv.ID, actorID(c))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// Revoke DELETE /api/v1/admin/invitations/:id
+func (h *Handler) Revoke(c *gin.Context) {
+	inv, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Revoke(inv.ID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Preview GET /api/v1/auth/invitations/:token
+func (h *Handler) Preview(c *gin.Context) {
+	p, err := h.svc.Preview(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Accept POST /api/v1/auth/invitations/accept
+func (h *Handler) Accept(c *gin.Context) {
+	var req AcceptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Accept(&req, c.ClientIP()); err != nil {
+		if password.WritePolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, adminuser.ErrPasswordReused) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invitation accepted"})
+}
+
+// load reads the :id invitation and checks the caller may act on its tenant.
+func (h *Handler) load(c *gin.Context) (*Invitation, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	inv, err := h.svc.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if !tenant.CanAccess(c, inv.TenantCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return inv, true
+}
+
+func actorID(c *gin.Context) uint {
+	if claims := middleware.GetClaims(c); claims != nil {
+		return claims.UserID
+	}
+	return 0
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrNotPending), errors.Is(err, ErrHasPassword):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoEmail), errors.Is(err, adminuser.ErrNotLocalUser), errors.Is(err, adminuser.ErrUserDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,66 @@
+package invitation
+
+import "time"
+
+func (Invitation) TableName() string { return "hyadmin_invitations" }
+
+// Invitation states. StateExpired is derived from ExpiresAt, never stored.
+const (
+	StatePending  = "pending"
+	StateAccepted = "accepted"
+	StateExpired  = "expired"
+	StateRevoked  = "revoked"
+)
+
+// Invitation lets a local user without a password choose one through an
+// emailed single-use link. There is one row per user; resending rotates the
+// token so earlier links stop working. Only the SHA-256 hash is stored.
+type Invitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	TenantCode string     `gorm:"index;not null" json:"tenant_code"`
+	Status     string     `gorm:"size:20;not null" json:"-"` // pending|accepted|revoked
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	InvitedBy  uint       `json:"invited_by"`
+	SentCount  int        `gorm:"default:0" json:"sent_count"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	State    string `gorm:"-" json:"state"`
+	Username string `gorm:"-" json:"username,omitempty"`
+}
+
+// StateAt reports the invitation state at now.
+func (i *Invitation) StateAt(now time.Time) string {
+	if i.Status == StatePending && !now.Before(i.ExpiresAt) {
+		return StateExpired
+	}
+	return i.Status
+}
+
+// CreateRequest creates a local user without a password and invites them.
+// TenantCode defaults to the caller's tenant.
+type CreateRequest struct {
+	TenantCode  string `json:"tenant_code"`
+	Username    string `json:"username" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	DisplayName string `json:"display_name"`
+}
+
+// Preview is what the invitee sees before accepting.
+type Preview struct {
+	TenantCode  string    `json:"tenant_code"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type AcceptRequest struct {
+	Token       string `json:"token" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+}
@@ -0,0 +1,85 @@
+package invitation
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Upsert stores the user's invitation, replacing a previous one.
+func (r *Repository) Upsert(i *Invitation) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "token_hash", "invited_by", "sent_count", "expires_at", "accepted_at", "revoked_at", "updated_at",
+		}),
+	}).Create(i).Error
+}
+
+func (r *Repository) FindByID(id uint) (*Invitation, error) {
+	var i Invitation
+	err := r.db.First(&i, id).Error
+	return &i, err
+}
+
+func (r *Repository) FindByUser(userID uint) (*Invitation, error) {
+	var i Invitation
+	err := r.db.Where("user_id = ?", userID).First(&i).Error
+	return &i, err
+}
+
+func (r *Repository) FindByHash(hash string) (*Invitation, error) {
+	var i Invitation
+	err := r.db.Where("token_hash = ?", hash).First(&i).Error
+	return &i, err
+}
+
+// List returns a tenant's invitations, optionally only those in state.
+func (r *Repository) List(tenantCode, state string, now time.Time) ([]Invitation, error) {
+	q := r.db.Where("tenant_code = ?", tenantCode)
+	switch state {
+	case "":
+	case StatePending:
+		q = q.Where("status = ? AND expires_at > ?", StatePending, now)
+	case StateExpired:
+		q = q.Where("status = ? AND expires_at <= ?", StatePending, now)
+	default:
+		q = q.Where("status = ?", state)
+	}
+	var list []Invitation
+	err := q.Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// Accept marks a pending, unexpired invitation accepted. It reports false when
+// the invitation was already used, revoked or has expired, so concurrent
+// redemptions cannot both win.
+func (r *Repository) Accept(id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&Invitation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, StatePending, now).
+		Updates(map[string]interface{}{"status": StateAccepted, "accepted_at": now})
+	return res.RowsAffected == 1, res.Error
+}
+
+// Release makes an accepted invitation pending again, e.g. when the password was rejected.
+func (r *Repository) Release(id uint) error {
+	return r.db.Model(&Invitation{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": StatePending, "accepted_at": nil}).Error
+}
+
+// Revoke revokes a pending invitation; it reports false if there was nothing to revoke.
+func (r *Repository) Revoke(id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&Invitation{}).
+		Where("id = ? AND status = ?", id, StatePending).
+		Updates(map[string]interface{}{"status": StateRevoked, "revoked_at": now})
+	return res.RowsAffected == 1, res.Error
+}
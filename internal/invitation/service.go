@@ -0,0 +1,259 @@
+// Package invitation lets administrators invite local users by email instead
+// of choosing their passwords: the invitee follows a single-use link, sets a
+// password (subject to the tenant's policy) and a display name.
+package invitation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/mail"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invitation: invalid or expired invitation")
+	ErrNotFound      = errors.New("invitation: not found")
+	ErrNotPending    = errors.New("invitation: invitation is no longer pending")
+	ErrUsernameTaken = errors.New("invitation: username already exists")
+	ErrNoEmail       = errors.New("invitation: user has no email address")
+	ErrHasPassword   = errors.New("invitation: user already has a password")
+)
+
+type Service struct {
+	repo     *Repository
+	users    *adminuser.Service
+	mailer   *mail.Service
+	settings *setting.Service
+	audit    *auditlog.Service
+}
+
+func NewService(repo *Repository, users *adminuser.Service, mailer *mail.Service, settings *setting.Service, audit *auditlog.Service) *Service {
+	return &Service{repo: repo, users: users, mailer: mailer, settings: settings, audit: audit}
+}
+
+// Create adds a local user without a password and emails them an invitation.
+func (s *Service) Create(ctx context.Context, req *CreateRequest, invitedBy uint) (*Invitation, error) {
+	if _, err := s.users.GetByUsername(req.TenantCode, req.Username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	u, err := s.users.Create(&adminuser.CreateUserRequest{
+		TenantCode:  req.TenantCode,
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Provider:    "local",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Invite(ctx, u.ID, invitedBy)
+}
+
+// Invite emails an invitation to an existing local user who has no password
+// yet. Any earlier link of the user stops working.
+func (s *Service) Invite(ctx context.Context, userID, invitedBy uint) (*Invitation, error) {
+	raw, err := s.users.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case raw.Provider != "local":
+		return nil, adminuser.ErrNotLocalUser
+	case raw.PasswordHash != "":
+		return nil, ErrHasPassword
+	case !raw.Enabled:
+		return nil, adminuser.ErrUserDisabled
+	}
+	u, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Email == "" {
+		return nil, ErrNoEmail
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ttl := time.Duration(s.settings.GetTenantInt(u.TenantCode, "auth.invitation.expire_hours", 72)) * time.Hour
+	inv := &Invitation{
+		UserID:     u.ID,
+		TenantCode: u.TenantCode,
+		Status:     StatePending,
+		TokenHash:  hashToken(secret),
+		InvitedBy:  invitedBy,
+		SentCount:  1,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if prev, err := s.repo.FindByUser(u.ID); err == nil {
+		inv.SentCount = prev.SentCount + 1
+	}
+	if err := s.repo.Upsert(inv); err != nil {
+		return nil, err
+	}
+
+	link := strings.ReplaceAll(s.settings.GetString("auth.invitation.url", "/accept-invitation?token={token}"), "{token}", secret)
+	platform := s.settings.GetString("ui.platform_name", "HySP Admin")
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: fmt.Sprintf("You are invited to %s", platform),
+		Body: fmt.Sprintf("Hello %s,\n\nAn account with username %q has been created for you on %s.\n"+
+			"Open the link below within %d hours to choose your password:\n\n%s\n\n"+
+			"If you did not expect this invitation, you can ignore this email.\n",
+			u.DisplayName, u.Username, platform, int(ttl.Hours()), link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return nil, fmt.Errorf("invitation: send mail: %w", err)
+	}
+	inv.State = StatePending
+	inv.Username = u.Username
+	return inv, nil
+}
+
+// Get returns an invitation by ID.
+func (s *Service) Get(id uint) (*Invitation, error) {
+	inv, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return inv, nil
+}
+
+// Resend issues a fresh link for a pending or expired invitation.
+func (s *Service) Resend(ctx context.Context, id, invitedBy uint) (*Invitation, error) {
+	inv, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if st := inv.StateAt(time.Now()); st != StatePending && st != StateExpired {
+		return nil, ErrNotPending
+	}
+	return s.Invite(ctx, inv.UserID, invitedBy)
+}
+
+// Revoke invalidates a pending invitation. The user is kept; it cannot sign
+// in until it is invited again or given a password.
+func (s *Service) Revoke(id uint) error {
+	ok, err := s.repo.Revoke(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err := s.repo.FindByID(id); err != nil {
+			return ErrNotFound
+		}
+		return ErrNotPending
+	}
+	return nil
+}
+
+// List returns a tenant's invitations, optionally filtered by state.
+func (s *Service) List(tenantCode, state string) ([]Invitation, error) {
+	now := time.Now()
+	list, err := s.repo.List(tenantCode, state, now)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(list))
+	for _, inv := range list {
+		ids = append(ids, inv.UserID)
+	}
+	names, err := s.users.Usernames(tenantCode, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].State = list[i].StateAt(now)
+		list[i].Username = names[list[i].UserID]
+	}
+	return list, nil
+}
+
+// Preview returns the account an invitation link is for, so the acceptance
+// form can show it.
+func (s *Service) Preview(token string) (*Preview, error) {
+	inv, err := s.repo.FindByHash(hashToken(token))
+	if err != nil || inv.StateAt(time.Now()) != StatePending {
+		return nil, ErrInvalidToken
+	}
+	u, err := s.users.GetByID(inv.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &Preview{
+		TenantCode:  u.TenantCode,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		ExpiresAt:   inv.ExpiresAt,
+	}, nil
+}
+
+// Accept redeems an invitation: it sets the password and, if given, the
+// display name. A rejected password leaves the invitation pending.
+func (s *Service) Accept(req *AcceptRequest, ip string) error {
+	inv, err := s.repo.FindByHash(hashToken(req.Token))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	u, err := s.users.GetUser(inv.UserID)
+	if err != nil || !u.Enabled {
+		return ErrInvalidToken
+	}
+	ok, err := s.repo.Accept(inv.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidToken
+	}
+	if err := s.users.ResetPassword(u.ID, req.Password, false, "invitation_accepted"); err != nil {
+		_ = s.repo.Release(inv.ID)
+		return err
+	}
+	if req.DisplayName != "" {
+		if err := s.users.Update(u.ID, &adminuser.UpdateUserRequest{DisplayName: req.DisplayName}); err != nil {
+			return err
+		}
+	}
+	s.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: u.TenantCode,
+		UserID:     u.ID,
+		Username:   u.Username,
+		Action:     "INVITATION_ACCEPTED",
+		Resource:   "auth",
+		ResourceID: fmt.Sprintf("%d", u.ID),
+		IP:         ip,
+	})
+	return nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("invitation: random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
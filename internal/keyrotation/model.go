@@ -0,0 +1,40 @@
+package keyrotation
+
+import "time"
+
+func (Progress) TableName() string { return "hyadmin_key_rotation_progress" }
+
+// Target is an encrypted column. Every column written with crypto.Encryptor
+// must be listed in Targets so that rotation re-encrypts it.
+type Target struct {
+	Table  string
+	Column string
+}
+
+func (t Target) String() string { return t.Table + "." + t.Column }
+
+// Targets are all Tink-encrypted columns of the admin DB.
+var Targets = []Target{
+	{Table: "hyadmin_users", Column: "display_name"},
+	{Table: "hyadmin_users", Column: "email"},
+	{Table: "hyadmin_users", Column: "totp_secret"},
+	{Table: "hyadmin_oidc_providers", Column: "client_secret"},
+}
+
+// Progress is the resume point of a rotation of one target to KeyID.
+type Progress struct {
+	Target    string `gorm:"primaryKey;size:200"`
+	KeyID     uint32 `gorm:"not null"`
+	LastID    uint   `gorm:"not null"`
+	Rotated   int64  `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// TargetReport counts the non-empty values of a target per key ID.
+// Keys are decimal key IDs, or "unknown" for values without a known key prefix.
+type TargetReport struct {
+	Target string           `json:"target"`
+	Total  int64            `json:"total"`
+	ByKey  map[string]int64 `json:"by_key"`
+	Stale  int64            `json:"stale"` // not on the primary key
+}
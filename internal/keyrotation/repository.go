@@ -0,0 +1,54 @@
+package keyrotation
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+type row struct {
+	ID    uint
+	Value string
+}
+
+// Batch returns up to n rows of t with ID greater than afterID, including
+// soft-deleted rows.
+func (r *Repository) Batch(t Target, afterID uint, n int) ([]row, error) {
+	var rows []row
+	err := r.db.Table(t.Table).
+		Select("id, COALESCE("+t.Column+", '') AS value").
+		Where("id > ?", afterID).
+		Order("id").Limit(n).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Replace swaps the value of one row only if it still holds old, so a
+// concurrent write by the running server is never overwritten.
+func (r *Repository) Replace(t Target, id uint, old, value string) (bool, error) {
+	res := r.db.Table(t.Table).
+		Where("id = ? AND "+t.Column+" = ?", id, old).
+		Update(t.Column, value)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *Repository) Progress(t Target) (*Progress, error) {
+	var p Progress
+	err := r.db.Where("target = ?", t.String()).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Progress{Target: t.String()}, nil
+	}
+	return &p, err
+}
+
+func (r *Repository) SaveProgress(p *Progress) error {
+	return r.db.Save(p).Error
+}
@@ -0,0 +1,164 @@
+// Package keyrotation re-encrypts PII columns after a new primary key has
+// been added to the Tink keyset, and reports values still on older keys.
+//
+// Rotation procedure: add a new key to TINK_KEYSET and make it primary,
+// keeping the old keys; restart the API so new writes use it; run
+// `hyadmin crypto rotate`; once `hyadmin crypto report` shows no stale values
+// the old keys may be disabled and later removed from the keyset.
+package keyrotation
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/robert7528/hycore/crypto"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+)
+
+// ErrNoKeyset is returned when PII encryption is disabled (empty TINK_KEYSET).
+var ErrNoKeyset = errors.New("keyrotation: no Tink keyset configured")
+
+// unknownKey labels values without a prefix of a key in the keyset
+// (RAW output prefix keys, plaintext, or keys already removed).
+const unknownKey = "unknown"
+
+type Service struct {
+	repo    *Repository
+	enc     crypto.Encryptor
+	primary uint32
+	keys    map[uint32]bool
+}
+
+// NewService loads keysetJSON; its primary key is the rotation target.
+func NewService(repo *Repository, keysetJSON string) (*Service, error) {
+	if keysetJSON == "" {
+		return nil, ErrNoKeyset
+	}
+	kh, err := insecurecleartextkeyset.Read(keyset.NewJSONReader(strings.NewReader(keysetJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("keyrotation: read keyset: %w", err)
+	}
+	enc, err := crypto.NewTinkEncryptor(keysetJSON)
+	if err != nil {
+		return nil, err
+	}
+	info := kh.KeysetInfo()
+	keys := make(map[uint32]bool, len(info.GetKeyInfo()))
+	for _, k := range info.GetKeyInfo() {
+		keys[k.GetKeyId()] = true
+	}
+	return &Service{repo: repo, enc: enc, primary: info.GetPrimaryKeyId(), keys: keys}, nil
+}
+
+// PrimaryKeyID is the key new ciphertexts are written with.
+func (s *Service) PrimaryKeyID() uint32 { return s.primary }
+
+// Rotate re-encrypts every value of every target that is not on the primary
+// key, batchSize rows at a time. Progress is checkpointed after each batch, so
+// an interrupted run resumes where it stopped; restart forces a full rescan.
+// progress, if set, is called after each batch.
+func (s *Service) Rotate(batchSize int, restart bool, progress func(p *Progress)) error {
+	for _, t := range Targets {
+		if err := s.rotateTarget(t, batchSize, restart, progress); err != nil {
+			return fmt.Errorf("keyrotation: %s: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) rotateTarget(t Target, batchSize int, restart bool, progress func(p *Progress)) error {
+	p, err := s.repo.Progress(t)
+	if err != nil {
+		return err
+	}
+	if restart || p.KeyID != s.primary {
+		p.KeyID, p.LastID, p.Rotated = s.primary, 0, 0
+	}
+	for {
+		rows, err := s.repo.Batch(t, p.LastID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return s.repo.SaveProgress(p)
+		}
+		for _, r := range rows {
+			if r.Value != "" && s.keyLabel(r.Value) != strconv.FormatUint(uint64(s.primary), 10) {
+				plain, err := s.enc.Decrypt(r.Value)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", r.ID, err)
+				}
+				value, err := s.enc.Encrypt(plain)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", r.ID, err)
+				}
+				ok, err := s.repo.Replace(t, r.ID, r.Value, value)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", r.ID, err)
+				}
+				if ok {
+					p.Rotated++
+				}
+			}
+			p.LastID = r.ID
+		}
+		if err := s.repo.SaveProgress(p); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(p)
+		}
+	}
+}
+
+// Report counts the values of every target per key ID.
+func (s *Service) Report(batchSize int) ([]TargetReport, error) {
+	primary := strconv.FormatUint(uint64(s.primary), 10)
+	reports := make([]TargetReport, 0, len(Targets))
+	for _, t := range Targets {
+		rep := TargetReport{Target: t.String(), ByKey: map[string]int64{}}
+		var afterID uint
+		for {
+			rows, err := s.repo.Batch(t, afterID, batchSize)
+			if err != nil {
+				return nil, fmt.Errorf("keyrotation: %s: %w", t, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			for _, r := range rows {
+				afterID = r.ID
+				if r.Value == "" {
+					continue
+				}
+				label := s.keyLabel(r.Value)
+				rep.Total++
+				rep.ByKey[label]++
+				if label != primary {
+					rep.Stale++
+				}
+			}
+		}
+		reports = append(reports, rep)
+	}
+	return reports, nil
+}
+
+// keyLabel returns the decimal ID of the keyset key that produced ciphertext,
+// read from its Tink output prefix, or "unknown".
+func (s *Service) keyLabel(ciphertext string) string {
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(b) < 5 || (b[0] != 0 && b[0] != 1) {
+		return unknownKey
+	}
+	id := binary.BigEndian.Uint32(b[1:5])
+	if !s.keys[id] {
+		return unknownKey
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,56 @@
+package ldapauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves a tenant's LDAP configuration. Its routes run behind
+// tenant.RequireAccess("code").
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// Get GET /api/v1/admin/tenants/:code/ldap
+func (h *Handler) Get(c *gin.Context) {
+	cfg, err := h.svc.Get(c.Param("code"))
+	if err != nil {
+		if errors.Is(err, ErrNotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// Put PUT /api/v1/admin/tenants/:code/ldap
+func (h *Handler) Put(c *gin.Context) {
+	var req ConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg, err := h.svc.Put(c.Param("code"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// Delete DELETE /api/v1/admin/tenants/:code/ldap
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Param("code")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,38 @@
+package ldapauth
+
+import "time"
+
+func (Config) TableName() string { return "hyadmin_ldap_configs" }
+
+// Config is a tenant's LDAP / Active Directory server. Users authenticate by
+// binding as BindDNTemplate with {username} substituted (DN-escaped), then
+// their entry is read from SearchBase with UserFilter.
+type Config struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	TenantCode      string    `gorm:"uniqueIndex;not null" json:"tenant_code"`
+	URL             string    `gorm:"not null" json:"url"`              // ldap://host:389 or ldaps://host:636
+	BindDNTemplate  string    `gorm:"not null" json:"bind_dn_template"` // e.g. uid={username},ou=people,dc=example,dc=com or {username}@corp.example.com
+	SearchBase      string    `gorm:"not null" json:"search_base"`      // e.g. dc=example,dc=com
+	UserFilter      string    `gorm:"default:'(uid={username})'" json:"user_filter"`
+	GroupAttr       string    `gorm:"default:'memberOf'" json:"group_attr"`
+	DisplayNameAttr string    `gorm:"default:'displayName'" json:"display_name_attr"`
+	EmailAttr       string    `gorm:"default:'mail'" json:"email_attr"`
+	StartTLS        bool      `gorm:"column:start_tls;default:false" json:"start_tls"`
+	InsecureSkipTLS bool      `gorm:"column:insecure_skip_tls;default:false" json:"insecure_skip_tls"` // dev only
+	Enabled         bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ConfigRequest struct {
+	URL             string `json:"url" binding:"required"`
+	BindDNTemplate  string `json:"bind_dn_template" binding:"required"`
+	SearchBase      string `json:"search_base" binding:"required"`
+	UserFilter      string `json:"user_filter"`
+	GroupAttr       string `json:"group_attr"`
+	DisplayNameAttr string `json:"display_name_attr"`
+	EmailAttr       string `json:"email_attr"`
+	StartTLS        bool   `json:"start_tls"`
+	InsecureSkipTLS bool   `json:"insecure_skip_tls"`
+	Enabled         *bool  `json:"enabled"`
+}
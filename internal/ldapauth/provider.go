@@ -0,0 +1,164 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	coreauth "github.com/robert7528/hycore/auth"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	localauth "github.com/hysp/hyadmin-api/internal/auth"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/provisioning"
+)
+
+const ioTimeout = 10 * time.Second
+
+// Provider implements coreauth.Provider for LDAP binds. Users are linked by
+// entry DN (AdminUser.ProviderID) and created/resynced via provisioning.
+type Provider struct {
+	svc         *Service
+	provisioner *provisioning.Service
+	guard       *lockout.Service
+	expiry      int
+}
+
+func NewProvider(svc *Service, provisioner *provisioning.Service, guard *lockout.Service, expiryHours int) *Provider {
+	return &Provider{svc: svc, provisioner: provisioner, guard: guard, expiry: expiryHours}
+}
+
+func (p *Provider) Name() string { return "ldap" }
+
+// Authenticate expects tenant_code, username and password in creds. Like
+// LocalProvider it is throttled per user and IP and reports every bad login
+// as localauth.ErrInvalidCredentials.
+func (p *Provider) Authenticate(_ context.Context, creds map[string]string) (*coreauth.Claims, error) {
+	tenantCode := creds["tenant_code"]
+	username := creds["username"]
+	if tenantCode == "" || username == "" {
+		return nil, fmt.Errorf("auth: tenant_code and username are required")
+	}
+	cfg, err := p.svc.Get(tenantCode)
+	if err != nil || !cfg.Enabled {
+		return nil, ErrNotConfigured
+	}
+
+	attempt := lockout.Attempt{TenantCode: tenantCode, Username: username, IP: creds["client_ip"]}
+	if err := p.guard.Check(attempt); err != nil {
+		return nil, err
+	}
+	id, err := p.bind(cfg, username, creds["password"])
+	if err != nil {
+		var le *ldap.Error
+		if errors.As(err, &le) && le.ResultCode == ldap.LDAPResultInvalidCredentials || errors.Is(err, errEmptyPassword) {
+			p.guard.Fail(attempt, "invalid_password")
+			return nil, localauth.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	u, err := p.provisioner.Resolve(id)
+	if u != nil {
+		attempt.UserID = u.ID
+	}
+	if err != nil {
+		if errors.Is(err, adminuser.ErrUserDisabled) {
+			p.guard.Fail(attempt, "user_disabled")
+			return nil, localauth.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	p.guard.Succeed(attempt)
+	return coreauth.NewClaims(u.ID, u.TenantCode, u.Username, "ldap", p.expiry), nil
+}
+
+var errEmptyPassword = errors.New("ldap: empty password")
+
+// bind authenticates as the user and reads their entry.
+func (p *Provider) bind(cfg *Config, username, password string) (*provisioning.Identity, error) {
+	// An empty password would be an unauthenticated bind, which most servers accept.
+	if password == "" {
+		return nil, errEmptyPassword
+	}
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	bindDN := strings.ReplaceAll(cfg.BindDNTemplate, "{username}", ldap.EscapeDN(username))
+	if err := conn.Bind(bindDN, password); err != nil {
+		return nil, err
+	}
+
+	attrs := []string{"dn", cfg.DisplayNameAttr, cfg.EmailAttr, cfg.GroupAttr, "cn"}
+	filter := strings.ReplaceAll(cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+	res, err := conn.Search(ldap.NewSearchRequest(
+		cfg.SearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ioTimeout.Seconds()), false, filter, attrs, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search user: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, fmt.Errorf("ldap: user filter matched %d entries", len(res.Entries))
+	}
+	e := res.Entries[0]
+
+	groups := e.GetAttributeValues(cfg.GroupAttr)
+	groupCNs := make([]string, 0, len(groups))
+	for _, g := range groups {
+		if dn, err := ldap.ParseDN(g); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			groupCNs = append(groupCNs, dn.RDNs[0].Attributes[0].Value)
+		}
+	}
+	displayName := e.GetAttributeValue(cfg.DisplayNameAttr)
+	if displayName == "" {
+		displayName = e.GetAttributeValue("cn")
+	}
+	return &provisioning.Identity{
+		TenantCode:  cfg.TenantCode,
+		Provider:    "ldap",
+		Subject:     strings.ToLower(e.DN),
+		Username:    username,
+		DisplayName: displayName,
+		Email:       e.GetAttributeValue(cfg.EmailAttr),
+		Claims: map[string]interface{}{
+			"dn":        e.DN,
+			"groups":    groups,   // full group DNs from GroupAttr
+			"group_cns": groupCNs, // first RDN value of each group DN
+			"username":  username,
+		},
+	}, nil
+}
+
+func dial(cfg *Config) (*ldap.Conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Hostname()
+	tc := &tls.Config{ServerName: host, InsecureSkipVerify: cfg.InsecureSkipTLS, MinVersion: tls.VersionTLS12}
+	conn, err := ldap.DialURL(cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ioTimeout}),
+		ldap.DialWithTLSConfig(tc),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial: %w", err)
+	}
+	conn.SetTimeout(ioTimeout)
+	if cfg.StartTLS {
+		if err := conn.StartTLS(tc); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: starttls: %w", err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,311 @@
+package ldapauth
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeEntry is a directory entry served by fakeServer.
+type fakeEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+// fakeServer is a minimal in-process LDAP server: it answers simple binds
+// from passwords and searches with equality filters, and records what it
+// received so tests can check how requests were built.
+type fakeServer struct {
+	ln        net.Listener
+	passwords map[string]string // bind DN -> password
+	entries   []fakeEntry
+
+	mu      sync.Mutex
+	binds   []string
+	filters []*ber.Packet
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, passwords: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) url() string { return "ldap://" + s.ln.Addr().String() }
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+		id := req.Children[0].Value.(int64)
+		op := req.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := string(op.Children[1].Data.Bytes())
+			password := string(op.Children[2].Data.Bytes())
+			s.mu.Lock()
+			s.binds = append(s.binds, dn)
+			s.mu.Unlock()
+			code := ldap.LDAPResultSuccess
+			if want, ok := s.passwords[dn]; !ok || want != password || password == "" {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			s.write(conn, id, result(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			filter := op.Children[6]
+			s.mu.Lock()
+			s.filters = append(s.filters, filter)
+			s.mu.Unlock()
+			for _, e := range s.entries {
+				if matches(filter, e) {
+					s.write(conn, id, e.packet())
+				}
+			}
+			s.write(conn, id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *fakeServer) write(conn net.Conn, id int64, op *ber.Packet) {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	msg.AppendChild(op)
+	_, _ = conn.Write(msg.Bytes())
+}
+
+func (s *fakeServer) received() (binds []string, filters []*ber.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...), append([]*ber.Packet(nil), s.filters...)
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "LDAPResult")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return op
+}
+
+func (e fakeEntry) packet() *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "SearchResultEntry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "objectName"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, vals := range e.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range vals {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "val"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+// matches evaluates the filters the tests use: equality, and, or and present.
+func matches(f *ber.Packet, e fakeEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matches(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matches(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e.attrs[string(f.Data.Bytes())]) > 0
+	case ldap.FilterEqualityMatch:
+		attr, value := equality(f)
+		for _, v := range e.attrs[attr] {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equality(f *ber.Packet) (attr, value string) {
+	return string(f.Children[0].Data.Bytes()), string(f.Children[1].Data.Bytes())
+}
+
+func testConfig(url string) *Config {
+	return &Config{
+		TenantCode:      "acme",
+		URL:             url,
+		BindDNTemplate:  "uid={username},ou=people,dc=example,dc=com",
+		SearchBase:      "dc=example,dc=com",
+		UserFilter:      "(uid={username})",
+		GroupAttr:       "memberOf",
+		DisplayNameAttr: "displayName",
+		EmailAttr:       "mail",
+	}
+}
+
+func TestBindReadsEntry(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.passwords["uid=alice,ou=people,dc=example,dc=com"] = "s3cret"
+	srv.entries = []fakeEntry{{
+		dn: "uid=alice,ou=People,dc=example,dc=com",
+		attrs: map[string][]string{
+			"uid":         {"alice"},
+			"cn":          {"Alice A."},
+			"displayName": {"Alice Anderson"},
+			"mail":        {"alice@example.com"},
+			"memberOf":    {"cn=admins,ou=groups,dc=example,dc=com", "cn=ops,ou=groups,dc=example,dc=com"},
+		},
+	}}
+
+	id, err := (&Provider{}).bind(testConfig(srv.url()), "alice", "s3cret")
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if id.TenantCode != "acme" || id.Provider != "ldap" || id.Username != "alice" {
+		t.Errorf("identity = %+v", id)
+	}
+	if id.Subject != "uid=alice,ou=people,dc=example,dc=com" {
+		t.Errorf("Subject = %q, want the lowercased entry DN", id.Subject)
+	}
+	if id.DisplayName != "Alice Anderson" || id.Email != "alice@example.com" {
+		t.Errorf("DisplayName, Email = %q, %q", id.DisplayName, id.Email)
+	}
+	cns, _ := id.Claims["group_cns"].([]string)
+	if strings.Join(cns, ",") != "admins,ops" {
+		t.Errorf("group_cns = %v, want [admins ops]", cns)
+	}
+}
+
+func TestBindFallsBackToCN(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.passwords["uid=bob,ou=people,dc=example,dc=com"] = "pw"
+	srv.entries = []fakeEntry{{
+		dn:    "uid=bob,ou=people,dc=example,dc=com",
+		attrs: map[string][]string{"uid": {"bob"}, "cn": {"Bob B."}},
+	}}
+
+	id, err := (&Provider{}).bind(testConfig(srv.url()), "bob", "pw")
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if id.DisplayName != "Bob B." {
+		t.Errorf("DisplayName = %q, want the cn", id.DisplayName)
+	}
+}
+
+func TestBindRejectsWrongPassword(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.passwords["uid=alice,ou=people,dc=example,dc=com"] = "s3cret"
+
+	_, err := (&Provider{}).bind(testConfig(srv.url()), "alice", "wrong")
+	var le *ldap.Error
+	if !errors.As(err, &le) || le.ResultCode != ldap.LDAPResultInvalidCredentials {
+		t.Fatalf("err = %v, want invalid credentials", err)
+	}
+	if _, filters := srv.received(); len(filters) != 0 {
+		t.Errorf("searched after a failed bind")
+	}
+}
+
+func TestBindRejectsEmptyPassword(t *testing.T) {
+	srv := newFakeServer(t)
+	// The server would accept an unauthenticated bind for this DN.
+	srv.passwords["uid=alice,ou=people,dc=example,dc=com"] = ""
+
+	_, err := (&Provider{}).bind(testConfig(srv.url()), "alice", "")
+	if !errors.Is(err, errEmptyPassword) {
+		t.Fatalf("err = %v, want errEmptyPassword", err)
+	}
+	if binds, _ := srv.received(); len(binds) != 0 {
+		t.Errorf("sent binds %q, want none", binds)
+	}
+}
+
+func TestBindRequiresOneEntry(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.passwords["uid=alice,ou=people,dc=example,dc=com"] = "s3cret"
+	srv.entries = []fakeEntry{
+		{dn: "uid=alice,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"alice"}}},
+		{dn: "uid=alice,ou=staff,dc=example,dc=com", attrs: map[string][]string{"uid": {"alice"}}},
+	}
+
+	if _, err := (&Provider{}).bind(testConfig(srv.url()), "alice", "s3cret"); err == nil {
+		t.Fatal("bind succeeded with two matching entries")
+	}
+}
+
+func TestBindEscapesUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		bindDN   string
+	}{
+		{"*", `uid=*,ou=people,dc=example,dc=com`},
+		{"alice)(uid=*", `uid=alice)(uid=*,ou=people,dc=example,dc=com`},
+		{"x,ou=admins", `uid=x\,ou=admins,ou=people,dc=example,dc=com`},
+		{`back\slash`, `uid=back\\slash,ou=people,dc=example,dc=com`},
+		{"*)(|(uid=*", `uid=*)(|(uid=*,ou=people,dc=example,dc=com`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			srv := newFakeServer(t)
+			srv.passwords[tt.bindDN] = "pw"
+			// Every entry would match if the username were spliced into the
+			// filter unescaped.
+			srv.entries = []fakeEntry{
+				{dn: "uid=alice,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"alice"}}},
+				{dn: "uid=bob,ou=people,dc=example,dc=com", attrs: map[string][]string{"uid": {"bob"}}},
+			}
+
+			_, err := (&Provider{}).bind(testConfig(srv.url()), tt.username, "pw")
+			if err == nil || !strings.Contains(err.Error(), "matched 0 entries") {
+				t.Errorf("err = %v, want no matching entry", err)
+			}
+			binds, filters := srv.received()
+			if len(binds) != 1 || binds[0] != tt.bindDN {
+				t.Errorf("bind DNs = %q, want [%q]", binds, tt.bindDN)
+			}
+			if len(filters) != 1 {
+				t.Fatalf("got %d searches, want 1", len(filters))
+			}
+			if filters[0].Tag != ldap.FilterEqualityMatch {
+				t.Fatalf("filter is %s, want a single equality match", ldap.FilterMap[uint64(filters[0].Tag)])
+			}
+			if attr, value := equality(filters[0]); attr != "uid" || value != tt.username {
+				t.Errorf("filter = (%s=%s), want (uid=%s) with the literal username", attr, value, tt.username)
+			}
+		})
+	}
+}
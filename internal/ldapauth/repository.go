@@ -0,0 +1,35 @@
+package ldapauth
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindByTenant(tenantCode string) (*Config, error) {
+	var c Config
+	err := r.db.Where("tenant_code = ?", tenantCode).First(&c).Error
+	return &c, err
+}
+
+// Upsert creates or replaces the tenant's config.
+func (r *Repository) Upsert(c *Config) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"url", "bind_dn_template", "search_base", "user_filter", "group_attr",
+			"display_name_attr", "email_attr", "start_tls", "insecure_skip_tls", "enabled", "updated_at",
+		}),
+	}).Create(c).Error
+}
+
+func (r *Repository) Delete(tenantCode string) error {
+	return r.db.Where("tenant_code = ?", tenantCode).Delete(&Config{}).Error
+}
@@ -0,0 +1,71 @@
+// Package ldapauth authenticates users against a tenant's LDAP or Active
+// Directory server by binding as the user.
+package ldapauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrNotConfigured = errors.New("ldap: not configured for tenant")
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Get(tenantCode string) (*Config, error) {
+	c, err := s.repo.FindByTenant(tenantCode)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotConfigured
+	}
+	return c, err
+}
+
+func (s *Service) Put(tenantCode string, req *ConfigRequest) (*Config, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return nil, fmt.Errorf("ldap: url must be ldap://host[:port] or ldaps://host[:port]")
+	}
+	if !strings.Contains(req.BindDNTemplate, "{username}") {
+		return nil, fmt.Errorf("ldap: bind_dn_template must contain {username}")
+	}
+	if u.Scheme == "ldaps" && req.StartTLS {
+		return nil, fmt.Errorf("ldap: start_tls cannot be combined with ldaps://")
+	}
+	c := &Config{
+		TenantCode:      tenantCode,
+		URL:             req.URL,
+		BindDNTemplate:  req.BindDNTemplate,
+		SearchBase:      req.SearchBase,
+		UserFilter:      orDefault(req.UserFilter, "(uid={username})"),
+		GroupAttr:       orDefault(req.GroupAttr, "memberOf"),
+		DisplayNameAttr: orDefault(req.DisplayNameAttr, "displayName"),
+		EmailAttr:       orDefault(req.EmailAttr, "mail"),
+		StartTLS:        req.StartTLS,
+		InsecureSkipTLS: req.InsecureSkipTLS,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.repo.Upsert(c); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByTenant(tenantCode)
+}
+
+func (s *Service) Delete(tenantCode string) error {
+	return s.repo.Delete(tenantCode)
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
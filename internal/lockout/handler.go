@@ -0,0 +1,56 @@
+package lockout
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+)
+
+type Handler struct {
+	svc   *Service
+	users *adminuser.Service
+}
+
+func NewHandler(svc *Service, users *adminuser.Service) *Handler {
+	return &Handler{svc: svc, users: users}
+}
+
+// Status GET /api/v1/admin/users/:id/lockout
+func (h *Handler) Status(c *gin.Context) {
+	u, ok := h.user(c)
+	if !ok {
+		return
+	}
+	until := h.svc.LockedUntil(u.TenantCode, u.Username)
+	c.JSON(http.StatusOK, gin.H{"locked": until != nil, "locked_until": until})
+}
+
+// Unlock POST /api/v1/admin/users/:id/unlock
+func (h *Handler) Unlock(c *gin.Context) {
+	u, ok := h.user(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.UnlockUser(u.TenantCode, u.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "unlocked"})
+}
+
+func (h *Handler) user(c *gin.Context) (*adminuser.AdminUserDTO, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	u, err := h.users.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	return u, true
+}
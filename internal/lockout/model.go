@@ -0,0 +1,43 @@
+package lockout
+
+import (
+	"fmt"
+	"time"
+)
+
+func (Throttle) TableName() string { return "hyadmin_login_throttles" }
+
+// Throttle counts consecutive failed logins for one key:
+// "user:{tenant}/{username}" or "ip:{addr}". Keys exist for unknown usernames
+// too, so lockout behaviour does not reveal which accounts exist.
+type Throttle struct {
+	Key           string     `gorm:"primaryKey;size:400" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// Attempt identifies a login attempt.
+type Attempt struct {
+	TenantCode string
+	Username   string
+	IP         string
+	UserID     uint // 0 when the user is unknown
+}
+
+func (a Attempt) userKey() string { return UserKey(a.TenantCode, a.Username) }
+func (a Attempt) ipKey() string   { return "ip:" + a.IP }
+
+// UserKey is the throttle key for a tenant user.
+func UserKey(tenantCode, username string) string {
+	return fmt.Sprintf("user:%s/%s", tenantCode, username)
+}
+
+// LockedError is returned while a user or source IP is locked out.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("lockout: too many failed attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
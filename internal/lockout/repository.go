@@ -0,0 +1,46 @@
+package lockout
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Find(key string) (*Throttle, error) {
+	var t Throttle
+	err := r.db.Where("key = ?", key).First(&t).Error
+	return &t, err
+}
+
+// Increment atomically adds a failure, restarting the count when the previous
+// failure is older than windowStart, and returns the updated row.
+func (r *Repository) Increment(key string, now, windowStart time.Time) (*Throttle, error) {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN hyadmin_login_throttles.last_failure_at < ? THEN 1 ELSE hyadmin_login_throttles.failures + 1 END", windowStart),
+			"last_failure_at": now,
+		}),
+	}).Create(&Throttle{Key: key, Failures: 1, LastFailureAt: now}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.Find(key)
+}
+
+func (r *Repository) Lock(key string, until time.Time) error {
+	return r.db.Model(&Throttle{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (r *Repository) Reset(key string) error {
+	return r.db.Where("key = ?", key).Delete(&Throttle{}).Error
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+// ErrNotConfigured is returned while mail.driver is "none", the default:
+// nothing is sent until an operator picks a driver.
+var ErrNotConfigured = errors.New("mail: no driver configured (mail.driver = none)")
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message. Implementations: SMTPMailer, FileMailer, LogMailer.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Service picks the Mailer configured by the mail.driver setting
+// ("none" | "log" | "file" | "smtp") at send time, so the driver can be
+// switched without a restart.
+type Service struct {
+	settings *setting.Service
+	log      *zap.Logger
+}
+
+func NewService(settings *setting.Service, log *zap.Logger) *Service {
+	return &Service{settings: settings, log: log}
+}
+
+func (s *Service) Send(ctx context.Context, msg Message) error {
+	m, err := s.mailer()
+	if err != nil {
+		return err
+	}
+	return m.Send(ctx, msg)
+}
+
+func (s *Service) mailer() (Mailer, error) {
+	switch driver := s.settings.GetString("mail.driver", "none"); driver {
+	case "none", "":
+		return nil, ErrNotConfigured
+	case "log":
+		return &LogMailer{log: s.log}, nil
+	case "file":
+		return &FileMailer{Dir: s.settings.GetString("mail.file.dir", "outbox")}, nil
+	case "smtp":
+		return NewSMTPMailer(s.settings), nil
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", driver)
+	}
+}
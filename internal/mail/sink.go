@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LogMailer writes messages to the application log. Intended for development only:
+// message bodies (and any links in them) end up in the log.
+type LogMailer struct {
+	log *zap.Logger
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.log.Info("mail (log driver)",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// FileMailer drops each message as an .eml file into Dir (a local outbox).
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("mail: create outbox: %w", err)
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().UTC().Format("20060102T150405.000000000"))
+	return os.WriteFile(filepath.Join(m.Dir, name), render("", msg), 0o600)
+}
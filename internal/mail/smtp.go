@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+// SMTPMailer sends through an SMTP relay configured by the mail.smtp.* settings.
+// The password is read from the MAIL_SMTP_PASSWORD env var so it never lands in the DB.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(settings *setting.Service) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     settings.GetString("mail.smtp.host", "localhost"),
+		Port:     settings.GetInt("mail.smtp.port", 587),
+		Username: settings.GetString("mail.smtp.username", ""),
+		Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+		From:     settings.GetString("mail.from", "no-reply@localhost"),
+	}
+}
+
+// Send uses net/smtp, which upgrades to STARTTLS when the server offers it
+// and refuses PLAIN auth over an unencrypted connection.
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	to := headerSafe.Replace(msg.To)
+	if err := smtp.SendMail(addr, auth, m.From, []string{to}, render(m.From, msg)); err != nil {
+		return fmt.Errorf("mail: smtp send: %w", err)
+	}
+	return nil
+}
+
+// headerSafe strips line breaks so header values cannot inject extra headers.
+var headerSafe = strings.NewReplacer("\r", "", "\n", "")
+
+// render builds an RFC 5322 message with a UTF-8 plain-text body.
+func render(from string, msg Message) []byte {
+	msg.To = headerSafe.Replace(msg.To)
+	msg.Subject = headerSafe.Replace(msg.Subject)
+	var b bytes.Buffer
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,138 @@
+package mfa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robert7528/hycore/middleware"
+
+	"github.com/hysp/hyadmin-api/internal/lockout"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// Status GET /api/v1/profile/mfa
+func (h *Handler) Status(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	st, err := h.svc.Status(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// SetupTOTP POST /api/v1/profile/mfa/totp/setup
+func (h *Handler) SetupTOTP(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	setup, err := h.svc.SetupTOTP(claims.UserID)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTOTP POST /api/v1/profile/mfa/totp/confirm
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.ConfirmTOTP(claims.UserID, claims.ID, req.Code)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "mfa enabled", "recovery_codes": codes})
+}
+
+// DisableTOTP POST /api/v1/profile/mfa/totp/disable
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.DisableTOTP(claims.UserID, req.Code, c.ClientIP()); err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "mfa disabled"})
+}
+
+// RegenerateRecoveryCodes POST /api/v1/profile/mfa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(claims.UserID, req.Code, c.ClientIP())
+	if err != nil {
+		if abortLocked(c, err) {
+			return
+		}
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// abortLocked answers 429 with Retry-After when err is a lockout.
+func abortLocked(c *gin.Context, err error) bool {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", fmt.Sprintf("%d", int(locked.RetryAfter.Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts"})
+	return true
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrAlreadyEnabled), errors.Is(err, ErrNotEnabled),
+		errors.Is(err, ErrNoPendingSetup), errors.Is(err, ErrRequired):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,32 @@
+package mfa
+
+import "time"
+
+func (RecoveryCode) TableName() string { return "hyadmin_mfa_recovery_codes" }
+
+// RecoveryCode is a single-use fallback for a lost authenticator.
+// Only the SHA-256 hash is stored; plaintext codes are shown once.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TOTPSetup is returned when enrollment starts.
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
+}
+
+// Status summarizes a user's MFA enrollment.
+type Status struct {
+	TOTPEnabled            bool  `json:"totp_enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+	Required               bool  `json:"required"` // enforced by tenant setting auth.mfa.required
+}
+
+type CodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
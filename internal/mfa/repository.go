@@ -0,0 +1,48 @@
+package mfa
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// ReplaceRecoveryCodes deletes all existing codes of a user and stores the new hashes.
+func (r *Repository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for _, h := range hashes {
+			if err := tx.Create(&RecoveryCode{UserID: userID, CodeHash: h}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repository) DeleteRecoveryCodes(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+}
+
+// UseRecoveryCode marks an unused code as used; it reports false if none matched.
+func (r *Repository) UseRecoveryCode(userID uint, hash string, now time.Time) (bool, error) {
+	res := r.db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) CountUnused(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,229 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/session"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrAlreadyEnabled = errors.New("mfa: totp already enabled")
+	ErrNotEnabled     = errors.New("mfa: totp not enabled")
+	ErrNoPendingSetup = errors.New("mfa: no pending totp setup")
+	ErrInvalidCode    = errors.New("mfa: invalid code")
+	ErrRequired       = errors.New("mfa: required by tenant policy")
+)
+
+type Service struct {
+	repo     *Repository
+	users    *adminuser.Service
+	sessions *session.Service
+	settings *setting.Service
+	guard    *lockout.Service
+}
+
+func NewService(repo *Repository, users *adminuser.Service, sessions *session.Service, settings *setting.Service, guard *lockout.Service) *Service {
+	return &Service{repo: repo, users: users, sessions: sessions, settings: settings, guard: guard}
+}
+
+// Required reports whether the tenant enforces MFA for all local users.
+func (s *Service) Required(tenantCode string) bool {
+	return s.settings.GetTenantBool(tenantCode, "auth.mfa.required", false)
+}
+
+// Enabled reports whether the user has completed TOTP enrollment.
+func (s *Service) Enabled(userID uint) bool {
+	_, enabled, _, err := s.users.TOTPState(userID)
+	return err == nil && enabled
+}
+
+func (s *Service) Status(userID uint) (*Status, error) {
+	u, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.repo.CountUnused(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{TOTPEnabled: u.MFAEnabled, RecoveryCodesRemaining: n, Required: s.Required(u.TenantCode)}, nil
+}
+
+// SetupTOTP generates a new pending secret. It does not take effect until ConfirmTOTP.
+func (s *Service) SetupTOTP(userID uint) (*TOTPSetup, error) {
+	u, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled {
+		return nil, ErrAlreadyEnabled
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetTOTPSecret(userID, secret); err != nil {
+		return nil, err
+	}
+	issuer := s.settings.GetString("ui.platform_name", "HySP Admin")
+	return &TOTPSetup{
+		Secret:     secret,
+		OtpauthURL: otpauthURL(issuer, u.TenantCode+"/"+u.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP activates the pending secret after the user proves possession,
+// lifts an mfa_enroll restriction on the current session and returns fresh recovery codes.
+func (s *Service) ConfirmTOTP(userID uint, sessionID, code string) ([]string, error) {
+	secret, enabled, lastStep, err := s.users.TOTPState(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if secret == "" {
+		return nil, ErrNoPendingSetup
+	}
+	step, ok := validateTOTP(secret, code, time.Now(), lastStep)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	if ok, err := s.users.ConsumeTOTPStep(userID, step); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrInvalidCode
+	}
+	if err := s.users.EnableMFA(userID); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID != "" {
+		if err := s.sessions.RemoveScope(sessionID, session.ScopeMFAEnroll); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns MFA off after verifying a current TOTP or recovery code.
+// Wrong codes count towards the login lockout, as they do at login.
+func (s *Service) DisableTOTP(userID uint, code, ip string) error {
+	u, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled {
+		return ErrNotEnabled
+	}
+	if s.Required(u.TenantCode) {
+		return ErrRequired
+	}
+	if err := s.verifyThrottled(u, code, ip); err != nil {
+		return err
+	}
+	if err := s.users.DisableMFA(userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteRecoveryCodes(userID)
+}
+
+// RegenerateRecoveryCodes invalidates all previous recovery codes.
+// Wrong codes count towards the login lockout, as they do at login.
+func (s *Service) RegenerateRecoveryCodes(userID uint, code, ip string) ([]string, error) {
+	u, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyThrottled(u, code, ip); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(userID)
+}
+
+// verifyThrottled runs Verify behind the lockout guard, so that a stolen
+// access token cannot be used to guess codes without limit.
+func (s *Service) verifyThrottled(u *adminuser.AdminUserDTO, code, ip string) error {
+	attempt := lockout.Attempt{TenantCode: u.TenantCode, Username: u.Username, IP: ip, UserID: u.ID}
+	if err := s.guard.Check(attempt); err != nil {
+		return err
+	}
+	if err := s.Verify(u.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			s.guard.Fail(attempt, "invalid_mfa_code")
+		}
+		return err
+	}
+	s.guard.Succeed(attempt)
+	return nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+func (s *Service) Verify(userID uint, code string) error {
+	secret, enabled, lastStep, err := s.users.TOTPState(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := validateTOTP(secret, code, time.Now(), lastStep); ok {
+		// Another request may have used this step since TOTPState was read.
+		ok, err := s.users.ConsumeTOTPStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+	used, err := s.repo.UseRecoveryCode(userID, hashCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *Service) newRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("mfa: random: %w", err)
+		}
+		raw := strings.ToLower(b32.EncodeToString(b)) // 8 chars
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashCode(codes[i])
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashCode normalizes a recovery code (case, dashes) before hashing.
+func hashCode(code string) string {
+	norm := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors.
+var rfcSecret = b32.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits.
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	key := []byte("12345678901234567890")
+	for unix, want := range tests {
+		if got := hotp(key, unix/totpPeriod); got != want {
+			t.Errorf("hotp at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+	code := func(s int64) string { return hotp(key, s) }
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current", code(step), 0, step, true},
+		{"previous step", code(step - 1), 0, step - 1, true},
+		{"next step", code(step + 1), 0, step + 1, true},
+		{"too old", code(step - 2), 0, 0, false},
+		{"too new", code(step + 2), 0, 0, false},
+		{"replayed", code(step), step, 0, false},
+		{"older than last used", code(step - 1), step, 0, false},
+		{"newer than last used", code(step + 1), step, step + 1, true},
+		{"short", code(step)[:5], 0, 0, false},
+		{"wrong", "000000", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := validateTOTP(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("validateTOTP = %d, %v; want %d, %v", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestTOTPReplay walks the login sequence: a code is accepted once, and the
+// step it matched then becomes the floor for the next login.
+func TestTOTPReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	c := hotp([]byte("12345678901234567890"), now.Unix()/totpPeriod)
+
+	step, ok := validateTOTP(rfcSecret, c, now, 0)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+	if _, ok := validateTOTP(rfcSecret, c, now.Add(10*time.Second), step); ok {
+		t.Fatal("the same code was accepted twice")
+	}
+	if _, ok := validateTOTP(rfcSecret, c, now.Add(totpPeriod*time.Second), step); ok {
+		t.Fatal("the same code was accepted again within the skew window")
+	}
+}
+
+func TestValidateTOTPBadSecret(t *testing.T) {
+	if _, ok := validateTOTP("not base32!", "123456", time.Now(), 0); ok {
+		t.Fatal("accepted a code for an undecodable secret")
+	}
+}
@@ -0,0 +1,198 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	localauth "github.com/hysp/hyadmin-api/internal/auth"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+// stateCookie carries the state of a pending login in the browser that
+// started it, so a callback URL replayed in another browser is rejected.
+const stateCookie = "hyadmin_oidc_state"
+
+// Handler serves the OIDC login flow and the per-tenant provider configs.
+// The config routes run behind tenant.RequireAccess("code").
+type Handler struct {
+	svc  *Service
+	auth *localauth.Service
+}
+
+func NewHandler(svc *Service, auth *localauth.Service) *Handler {
+	return &Handler{svc: svc, auth: auth}
+}
+
+// Providers GET /api/v1/auth/oidc/providers?tenant_code=...
+// Public: lists the enabled providers so the login page can render buttons.
+func (h *Handler) Providers(c *gin.Context) {
+	list, err := h.svc.List(c.Query("tenant_code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]gin.H, 0, len(list))
+	for _, p := range list {
+		if p.Enabled {
+			out = append(out, gin.H{"name": p.Name, "display_name": p.DisplayName})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": out})
+}
+
+// Start GET /api/v1/auth/oidc/:provider/start?tenant_code=...
+// Sets the state cookie, scoped to this provider's /start and /callback, and
+// redirects to the IdP. SameSite=Lax lets the cookie ride the IdP's top-level
+// redirect back to the callback.
+func (h *Handler) Start(c *gin.Context) {
+	authURL, state, err := h.svc.Start(c.Request.Context(), c.Query("tenant_code"), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, ErrProviderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, state, int(stateTTL.Seconds()), cookiePath(c), "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// cookiePath is the request path up to the provider segment, which covers
+// both /start and /callback.
+func cookiePath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	return path[:strings.LastIndex(path, "/")]
+}
+
+// Callback GET /api/v1/auth/oidc/:provider/callback?code=...&state=...
+// The state must match the cookie set by Start; otherwise the state is left
+// untouched for the browser that started the login.
+// Redirects to the provider's post_login_redirect with the tokens in the URL
+// fragment when configured, otherwise answers with the same JSON as /auth/login.
+func (h *Handler) Callback(c *gin.Context) {
+	name := c.Param("provider")
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": e, "error_description": c.Query("error_description")})
+		return
+	}
+	cookie, _ := c.Cookie(stateCookie)
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(c.Query("state"))) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrStateMismatch.Error()})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, "", -1, cookiePath(c), "", c.Request.TLS != nil, true)
+	tenantCode, err := h.svc.StateTenant(c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	creds := map[string]string{"provider": name, "state": c.Query("state"), "code": c.Query("code")}
+	info := session.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	res, err := h.auth.Login(c.Request.Context(), "oidc", creds, info)
+	if err != nil || res.Tokens == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "oidc login failed"})
+		return
+	}
+
+	if target := h.svc.PostLoginRedirect(tenantCode, name); target != "" {
+		fragment := url.Values{}
+		fragment.Set("token", res.Tokens.AccessToken)
+		fragment.Set("refresh_token", res.Tokens.RefreshToken)
+		fragment.Set("expires_in", strconv.Itoa(res.Tokens.ExpiresIn))
+		fragment.Set("token_type", res.Tokens.TokenType)
+		fragment.Set("provider", name)
+		c.Redirect(http.StatusFound, target+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":         res.Tokens.AccessToken,
+		"refresh_token": res.Tokens.RefreshToken,
+		"expires_in":    res.Tokens.ExpiresIn,
+		"token_type":    res.Tokens.TokenType,
+		"scope":         res.Tokens.Scope,
+		"provider":      name,
+	})
+}
+
+// List GET /api/v1/admin/tenants/:code/oidc-providers
+func (h *Handler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Create POST /api/v1/admin/tenants/:code/oidc-providers
+func (h *Handler) Create(c *gin.Context) {
+	var req ProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.svc.Create(c.Param("code"), &req)
+	if err != nil {
+		if errors.Is(err, ErrReservedName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// Update PUT /api/v1/admin/tenants/:code/oidc-providers/:id
+func (h *Handler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req ProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.svc.Update(c.Param("code"), uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProviderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, ErrReservedName):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Delete DELETE /api/v1/admin/tenants/:code/oidc-providers/:id
+func (h *Handler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Delete(c.Param("code"), uint(id)); err != nil {
+		if errors.Is(err, ErrProviderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
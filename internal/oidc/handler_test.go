@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/auth/oidc/:provider/start", h.Start)
+	r.GET("/api/v1/auth/oidc/:provider/callback", h.Callback)
+	return r
+}
+
+func TestStartSetsStateCookie(t *testing.T) {
+	svc := newTestService(t, newMockIssuer(t))
+	r := newTestRouter(NewHandler(svc, nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/corp/start?tenant_code=acme", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v, want one state cookie", cookies)
+	}
+	c := cookies[0]
+	if c.Name != stateCookie || c.Value != loc.Query().Get("state") {
+		t.Errorf("cookie %s=%q, want %s=%q", c.Name, c.Value, stateCookie, loc.Query().Get("state"))
+	}
+	if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie HttpOnly=%v SameSite=%v, want HttpOnly and Lax", c.HttpOnly, c.SameSite)
+	}
+	if c.Path != "/api/v1/auth/oidc/corp" {
+		t.Errorf("cookie path = %q, want it to cover the callback", c.Path)
+	}
+	if c.MaxAge != int(stateTTL/time.Second) {
+		t.Errorf("cookie MaxAge = %d, want %d", c.MaxAge, int(stateTTL/time.Second))
+	}
+}
+
+func TestCallbackRequiresStateCookie(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie func(state string) string // "" sends no cookie
+	}{
+		{"missing", func(string) string { return "" }},
+		{"other login", func(string) string { return "state-of-another-login" }},
+		{"prefix", func(s string) string { return s[:len(s)-1] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			svc := newTestService(t, issuer)
+			r := newTestRouter(NewHandler(svc, nil))
+
+			authURL, state, err := svc.Start(t.Context(), "acme", "corp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := issuer.authorize(t, authURL, "alice")
+
+			q := url.Values{"state": {state}, "code": {code}}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/corp/callback?"+q.Encode(), nil)
+			if v := tt.cookie(state); v != "" {
+				req.AddCookie(&http.Cookie{Name: stateCookie, Value: v})
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", w.Code)
+			}
+			// The rejected callback must not burn the login of the browser
+			// that holds the cookie.
+			if _, err := svc.StateTenant(state); err != nil {
+				t.Errorf("state was consumed: %v", err)
+			}
+			if issuer.exchanged() != 0 {
+				t.Errorf("code was exchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package oidc
+
+import "time"
+
+func (ProviderConfig) TableName() string { return "hyadmin_oidc_providers" }
+func (LoginState) TableName() string     { return "hyadmin_oidc_states" }
+
+// ProviderConfig is a tenant's OpenID Connect identity provider.
+// Name appears in /auth/oidc/:provider URLs and is stored as AdminUser.Provider.
+type ProviderConfig struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	TenantCode        string    `gorm:"uniqueIndex:uk_oidc_tenant_name;not null" json:"tenant_code"`
+	Name              string    `gorm:"uniqueIndex:uk_oidc_tenant_name;not null" json:"name"`
+	DisplayName       string    `json:"display_name"`
+	IssuerURL         string    `gorm:"not null" json:"issuer_url"`
+	ClientID          string    `gorm:"not null" json:"client_id"`
+	ClientSecretEnc   string    `gorm:"column:client_secret" json:"-"` // Tink-encrypted; empty for public clients
+	RedirectURL       string    `gorm:"not null" json:"redirect_url"`  // this API's callback, as registered at the IdP
+	Scopes            string    `gorm:"default:'openid profile email'" json:"scopes"`
+	PostLoginRedirect string    `json:"post_login_redirect"` // frontend URL; tokens are passed in the fragment
+	Enabled           bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// LoginState binds an in-flight authorization request to its PKCE verifier
+// and nonce. Rows are single-use and expire after a few minutes.
+type LoginState struct {
+	StateHash  string    `gorm:"primaryKey;size:64"`
+	TenantCode string    `gorm:"not null"`
+	Provider   string    `gorm:"not null"`
+	Verifier   string    `gorm:"not null"`
+	Nonce      string    `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+type ProviderRequest struct {
+	Name              string `json:"name" binding:"required"`
+	DisplayName       string `json:"display_name"`
+	IssuerURL         string `json:"issuer_url" binding:"required"`
+	ClientID          string `json:"client_id" binding:"required"`
+	ClientSecret      string `json:"client_secret"` // write-only; empty on update keeps the stored secret
+	RedirectURL       string `json:"redirect_url" binding:"required"`
+	Scopes            string `json:"scopes"`
+	PostLoginRedirect string `json:"post_login_redirect"`
+	Enabled           *bool  `json:"enabled"`
+}
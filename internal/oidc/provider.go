@@ -0,0 +1,24 @@
+package oidc
+
+import (
+	"context"
+
+	coreauth "github.com/robert7528/hycore/auth"
+)
+
+// Authenticator adapts the OIDC callback to the coreauth.Provider interface
+// so auth.Service can open sessions for it like it does for LocalProvider.
+type Authenticator struct {
+	svc *Service
+}
+
+func NewAuthenticator(svc *Service) *Authenticator {
+	return &Authenticator{svc: svc}
+}
+
+func (a *Authenticator) Name() string { return "oidc" }
+
+// Authenticate expects provider, state and code in creds.
+func (a *Authenticator) Authenticate(ctx context.Context, creds map[string]string) (*coreauth.Claims, error) {
+	return a.svc.Callback(ctx, creds["provider"], creds["state"], creds["code"])
+}
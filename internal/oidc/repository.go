@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) List(tenantCode string) ([]ProviderConfig, error) {
+	var list []ProviderConfig
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("name").Find(&list).Error
+	return list, err
+}
+
+func (r *Repository) FindByID(id uint) (*ProviderConfig, error) {
+	var p ProviderConfig
+	err := r.db.First(&p, id).Error
+	return &p, err
+}
+
+func (r *Repository) FindByName(tenantCode, name string) (*ProviderConfig, error) {
+	var p ProviderConfig
+	err := r.db.Where("tenant_code = ? AND name = ?", tenantCode, name).First(&p).Error
+	return &p, err
+}
+
+func (r *Repository) Create(p *ProviderConfig) error {
+	return r.db.Create(p).Error
+}
+
+func (r *Repository) Save(p *ProviderConfig) error {
+	return r.db.Save(p).Error
+}
+
+func (r *Repository) Delete(id uint) error {
+	return r.db.Delete(&ProviderConfig{}, id).Error
+}
+
+func (r *Repository) CreateState(s *LoginState) error {
+	return r.db.Create(s).Error
+}
+
+func (r *Repository) FindState(hash string, now time.Time) (*LoginState, error) {
+	var s LoginState
+	err := r.db.Where("state_hash = ? AND expires_at > ?", hash, now).First(&s).Error
+	return &s, err
+}
+
+// TakeState deletes and returns an unexpired state, so each one can be redeemed once.
+func (r *Repository) TakeState(hash string, now time.Time) (*LoginState, error) {
+	var s LoginState
+	res := r.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", hash, now).
+		Delete(&s)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+// PurgeExpiredStates removes abandoned login attempts.
+func (r *Repository) PurgeExpiredStates(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&LoginState{}).Error
+}
@@ -0,0 +1,314 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	coreauth "github.com/robert7528/hycore/auth"
+	"github.com/robert7528/hycore/crypto"
+	"golang.org/x/oauth2"
+
+	"github.com/hysp/hyadmin-api/internal/provisioning"
+)
+
+const stateTTL = 10 * time.Minute
+
+var (
+	ErrProviderNotFound = errors.New("oidc: provider not found")
+	ErrReservedName     = errors.New("oidc: provider name is reserved")
+	ErrInvalidState     = errors.New("oidc: invalid or expired state")
+	ErrStateMismatch    = errors.New("oidc: state was not issued to this browser")
+)
+
+// reservedNames cannot be used for OIDC providers because they collide with
+// built-in AdminUser.Provider values.
+var reservedNames = map[string]bool{"local": true, "ldap": true, "oidc": true}
+
+// Service manages per-tenant OIDC provider configs and runs the
+// authorization-code + PKCE flow against them.
+type Service struct {
+	repo        *Repository
+	provisioner *provisioning.Service
+	encryptor   crypto.Encryptor
+	expiry      int
+
+	mu        sync.Mutex
+	discovery map[string]*gooidc.Provider // by issuer URL
+}
+
+func NewService(repo *Repository, provisioner *provisioning.Service, enc crypto.Encryptor, expiryHours int) *Service {
+	return &Service{repo: repo, provisioner: provisioner, encryptor: enc, expiry: expiryHours, discovery: map[string]*gooidc.Provider{}}
+}
+
+func (s *Service) List(tenantCode string) ([]ProviderConfig, error) {
+	return s.repo.List(tenantCode)
+}
+
+func (s *Service) Create(tenantCode string, req *ProviderRequest) (*ProviderConfig, error) {
+	if reservedNames[req.Name] {
+		return nil, ErrReservedName
+	}
+	p := &ProviderConfig{TenantCode: tenantCode, Enabled: true}
+	if err := s.apply(p, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) Update(tenantCode string, id uint, req *ProviderRequest) (*ProviderConfig, error) {
+	p, err := s.find(tenantCode, id)
+	if err != nil {
+		return nil, err
+	}
+	if reservedNames[req.Name] {
+		return nil, ErrReservedName
+	}
+	oldIssuer := p.IssuerURL
+	if err := s.apply(p, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(p); err != nil {
+		return nil, err
+	}
+	s.forget(oldIssuer)
+	return p, nil
+}
+
+func (s *Service) Delete(tenantCode string, id uint) error {
+	p, err := s.find(tenantCode, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(p.ID)
+}
+
+func (s *Service) find(tenantCode string, id uint) (*ProviderConfig, error) {
+	p, err := s.repo.FindByID(id)
+	if err != nil || p.TenantCode != tenantCode {
+		return nil, ErrProviderNotFound
+	}
+	return p, nil
+}
+
+func (s *Service) apply(p *ProviderConfig, req *ProviderRequest) error {
+	p.Name = req.Name
+	p.DisplayName = req.DisplayName
+	p.IssuerURL = strings.TrimRight(req.IssuerURL, "/")
+	p.ClientID = req.ClientID
+	p.RedirectURL = req.RedirectURL
+	p.Scopes = req.Scopes
+	if p.Scopes == "" {
+		p.Scopes = "openid profile email"
+	}
+	p.PostLoginRedirect = req.PostLoginRedirect
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	if req.ClientSecret != "" {
+		enc, err := s.encryptor.Encrypt(req.ClientSecret)
+		if err != nil {
+			return err
+		}
+		p.ClientSecretEnc = enc
+	}
+	return nil
+}
+
+// Start records a new login attempt and returns the IdP authorization URL
+// and its state, which the caller must bind to the browser (see Handler.Start).
+func (s *Service) Start(ctx context.Context, tenantCode, name string) (authURL, state string, err error) {
+	p, err := s.repo.FindByName(tenantCode, name)
+	if err != nil || !p.Enabled {
+		return "", "", ErrProviderNotFound
+	}
+	op, err := s.provider(ctx, p.IssuerURL)
+	if err != nil {
+		return "", "", err
+	}
+	oc, err := s.oauthConfig(p, op)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	now := time.Now()
+	_ = s.repo.PurgeExpiredStates(now)
+	if err := s.repo.CreateState(&LoginState{
+		StateHash:  hashState(state),
+		TenantCode: tenantCode,
+		Provider:   name,
+		Verifier:   verifier,
+		Nonce:      nonce,
+		ExpiresAt:  now.Add(stateTTL),
+	}); err != nil {
+		return "", "", err
+	}
+	return oc.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Callback redeems the state, exchanges the code (with the PKCE verifier),
+// verifies the ID token and resolves its subject to an AdminUser (see provisioning.Service.Resolve).
+func (s *Service) Callback(ctx context.Context, name, state, code string) (*coreauth.Claims, error) {
+	p, id, err := s.identify(ctx, name, state, code)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.provisioner.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return coreauth.NewClaims(u.ID, u.TenantCode, u.Username, p.Name, s.expiry), nil
+}
+
+// identify runs the IdP side of Callback and returns the verified identity.
+func (s *Service) identify(ctx context.Context, name, state, code string) (*ProviderConfig, *provisioning.Identity, error) {
+	st, err := s.repo.TakeState(hashState(state), time.Now())
+	if err != nil || st.Provider != name {
+		return nil, nil, ErrInvalidState
+	}
+	p, err := s.repo.FindByName(st.TenantCode, name)
+	if err != nil || !p.Enabled {
+		return nil, nil, ErrProviderNotFound
+	}
+	op, err := s.provider(ctx, p.IssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	oc, err := s.oauthConfig(p, op)
+	if err != nil {
+		return nil, nil, err
+	}
+	tok, err := oc.Exchange(ctx, code, oauth2.VerifierOption(st.Verifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc: code exchange: %w", err)
+	}
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errors.New("oidc: token response has no id_token")
+	}
+	idt, err := op.Verifier(&gooidc.Config{ClientID: p.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc: verify id_token: %w", err)
+	}
+	if idt.Nonce != st.Nonce {
+		return nil, nil, errors.New("oidc: nonce mismatch")
+	}
+
+	var raw map[string]interface{}
+	if err := idt.Claims(&raw); err != nil {
+		return nil, nil, fmt.Errorf("oidc: decode claims: %w", err)
+	}
+	return p, identityFrom(p, idt.Subject, raw), nil
+}
+
+// identityFrom maps standard ID token claims onto a provisioning identity.
+func identityFrom(p *ProviderConfig, subject string, claims map[string]interface{}) *provisioning.Identity {
+	str := func(k string) string { v, _ := claims[k].(string); return v }
+	username := str("preferred_username")
+	if username == "" {
+		username = str("email")
+	}
+	return &provisioning.Identity{
+		TenantCode:  p.TenantCode,
+		Provider:    p.Name,
+		Subject:     subject,
+		Username:    username,
+		DisplayName: str("name"),
+		Email:       str("email"),
+		Claims:      claims,
+	}
+}
+
+// StateTenant returns the tenant of a pending login without redeeming it.
+func (s *Service) StateTenant(state string) (string, error) {
+	st, err := s.repo.FindState(hashState(state), time.Now())
+	if err != nil {
+		return "", ErrInvalidState
+	}
+	return st.TenantCode, nil
+}
+
+// PostLoginRedirect returns where the browser should land after a successful login, if configured.
+func (s *Service) PostLoginRedirect(tenantCode, name string) string {
+	p, err := s.repo.FindByName(tenantCode, name)
+	if err != nil {
+		return ""
+	}
+	return p.PostLoginRedirect
+}
+
+func (s *Service) oauthConfig(p *ProviderConfig, op *gooidc.Provider) (*oauth2.Config, error) {
+	var secret string
+	if p.ClientSecretEnc != "" {
+		var err error
+		if secret, err = s.encryptor.Decrypt(p.ClientSecretEnc); err != nil {
+			return nil, err
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: secret,
+		Endpoint:     op.Endpoint(),
+		RedirectURL:  p.RedirectURL,
+		Scopes:       strings.Fields(p.Scopes),
+	}, nil
+}
+
+// provider returns the discovered issuer metadata, cached per issuer URL.
+func (s *Service) provider(ctx context.Context, issuer string) (*gooidc.Provider, error) {
+	s.mu.Lock()
+	op, ok := s.discovery[issuer]
+	s.mu.Unlock()
+	if ok {
+		return op, nil
+	}
+	// Discovery must not be tied to the request context: the provider keeps
+	// using it to refresh the JWKS.
+	op, err := gooidc.NewProvider(context.WithoutCancel(ctx), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	s.mu.Lock()
+	s.discovery[issuer] = op
+	s.mu.Unlock()
+	return op, nil
+}
+
+func (s *Service) forget(issuer string) {
+	s.mu.Lock()
+	delete(s.discovery, issuer)
+	s.mu.Unlock()
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("oidc: random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
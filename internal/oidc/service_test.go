@@ -0,0 +1,327 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// grant is an authorization code the mock issuer handed out.
+type grant struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+// mockIssuer is an OpenID provider serving discovery, JWKS and a token
+// endpoint that enforces PKCE (S256) and single-use codes.
+type mockIssuer struct {
+	srv      *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	nonce  string // when set, issued ID tokens carry this nonce instead
+	issued int
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, clientID: "hyadmin", codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                m.srv.URL,
+			"authorization_endpoint":                m.srv.URL + "/authorize",
+			"token_endpoint":                        m.srv.URL + "/token",
+			"jwks_uri":                              m.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "alg": "RS256", "use": "sig",
+			"n": enc.EncodeToString(key.N.Bytes()),
+			"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+// authorize plays the user approving the login described by authURL and
+// returns the code the IdP would redirect back with.
+func (m *mockIssuer) authorize(t *testing.T, authURL, subject string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL lacks an S256 PKCE challenge: %s", authURL)
+	}
+	if q.Get("nonce") == "" || q.Get("state") == "" {
+		t.Fatalf("authorization URL lacks nonce or state: %s", authURL)
+	}
+	if q.Get("client_id") != m.clientID {
+		t.Fatalf("client_id = %q, want %q", q.Get("client_id"), m.clientID)
+	}
+	return m.grant(grant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: subject})
+}
+
+func (m *mockIssuer) grant(g grant) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := base64.RawURLEncoding.EncodeToString([]byte(g.subject + g.challenge))
+	m.codes[code] = g
+	return code
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	m.mu.Lock()
+	code := r.PostForm.Get("code")
+	g, ok := m.codes[code]
+	delete(m.codes, code)
+	nonce := m.nonce
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if nonce == "" {
+		nonce = g.nonce
+	}
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                m.srv.URL,
+		"aud":                m.clientID,
+		"sub":                g.subject,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Minute).Unix(),
+		"nonce":              nonce,
+		"preferred_username": g.subject,
+		"email":              g.subject + "@example.com",
+		"name":               "Test User",
+	})
+	tok.Header["kid"] = "k1"
+	idToken, err := tok.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	m.mu.Lock()
+	m.issued++
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "at", "token_type": "Bearer", "expires_in": 60, "id_token": idToken,
+	})
+}
+
+func (m *mockIssuer) exchanged() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.issued
+}
+
+func (m *mockIssuer) overrideNonce(nonce string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nonce = nonce
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newTestService returns a Service backed by in-memory SQLite with one
+// enabled public client "corp" of tenant "acme" registered at issuer.
+func newTestService(t *testing.T, issuer *mockIssuer) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection would get its own :memory: database
+	if err := db.AutoMigrate(&ProviderConfig{}, &LoginState{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(db)
+	if err := repo.Create(&ProviderConfig{
+		TenantCode:  "acme",
+		Name:        "corp",
+		IssuerURL:   issuer.srv.URL,
+		ClientID:    issuer.clientID,
+		RedirectURL: "https://admin.example.com/api/v1/auth/oidc/corp/callback",
+		Scopes:      "openid profile email",
+		Enabled:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repo, nil, nil, 8)
+}
+
+func TestLoginFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	svc := newTestService(t, issuer)
+	ctx := context.Background()
+
+	authURL, state, err := svc.Start(ctx, "acme", "corp")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !strings.HasPrefix(authURL, issuer.srv.URL+"/authorize?") {
+		t.Fatalf("authURL = %s", authURL)
+	}
+	u, _ := url.Parse(authURL)
+	if u.Query().Get("state") != state {
+		t.Fatalf("state in URL = %q, returned %q", u.Query().Get("state"), state)
+	}
+	code := issuer.authorize(t, authURL, "alice")
+
+	p, id, err := svc.identify(ctx, "corp", state, code)
+	if err != nil {
+		t.Fatalf("identify: %v", err)
+	}
+	if p.Name != "corp" || id.TenantCode != "acme" || id.Provider != "corp" {
+		t.Errorf("provider %q, identity %+v", p.Name, id)
+	}
+	if id.Subject != "alice" || id.Username != "alice" || id.Email != "alice@example.com" || id.DisplayName != "Test User" {
+		t.Errorf("identity = %+v", id)
+	}
+}
+
+func TestStateIsSingleUse(t *testing.T) {
+	issuer := newMockIssuer(t)
+	svc := newTestService(t, issuer)
+	ctx := context.Background()
+
+	authURL, state, err := svc.Start(ctx, "acme", "corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := issuer.authorize(t, authURL, "alice")
+	if _, _, err := svc.identify(ctx, "corp", state, code); err != nil {
+		t.Fatalf("first identify: %v", err)
+	}
+	if _, _, err := svc.identify(ctx, "corp", state, code); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("replayed state: err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestStateRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		state    func(issued string) string
+	}{
+		{"unknown", "corp", func(string) string { return "not-a-state" }},
+		{"empty", "corp", func(string) string { return "" }},
+		{"other provider", "other", func(s string) string { return s }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			svc := newTestService(t, issuer)
+			ctx := context.Background()
+
+			authURL, state, err := svc.Start(ctx, "acme", "corp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := issuer.authorize(t, authURL, "alice")
+			if _, _, err := svc.identify(ctx, tt.provider, tt.state(state), code); !errors.Is(err, ErrInvalidState) {
+				t.Fatalf("err = %v, want ErrInvalidState", err)
+			}
+			if issuer.exchanged() != 0 {
+				t.Errorf("code was exchanged for a rejected state")
+			}
+		})
+	}
+}
+
+func TestCodeRequiresVerifier(t *testing.T) {
+	issuer := newMockIssuer(t)
+	svc := newTestService(t, issuer)
+	ctx := context.Background()
+
+	// An intercepted code was issued for someone else's PKCE challenge: our
+	// verifier cannot redeem it.
+	authURL, state, err := svc.Start(ctx, "acme", "corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	sum := sha256.Sum256([]byte("attacker-verifier"))
+	code := issuer.grant(grant{
+		challenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+		nonce:     u.Query().Get("nonce"),
+		subject:   "alice",
+	})
+
+	_, _, err = svc.identify(ctx, "corp", state, code)
+	if err == nil || !strings.Contains(err.Error(), "code exchange") {
+		t.Fatalf("err = %v, want a failed code exchange", err)
+	}
+}
+
+func TestNonceMismatch(t *testing.T) {
+	issuer := newMockIssuer(t)
+	svc := newTestService(t, issuer)
+	ctx := context.Background()
+
+	authURL, state, err := svc.Start(ctx, "acme", "corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := issuer.authorize(t, authURL, "alice")
+	issuer.overrideNonce("nonce-of-another-login")
+
+	_, _, err = svc.identify(ctx, "corp", state, code)
+	if err == nil || !strings.Contains(err.Error(), "nonce mismatch") {
+		t.Fatalf("err = %v, want nonce mismatch", err)
+	}
+}
+
+func TestStartUnknownProvider(t *testing.T) {
+	svc := newTestService(t, newMockIssuer(t))
+	if _, _, err := svc.Start(context.Background(), "other-tenant", "corp"); !errors.Is(err, ErrProviderNotFound) {
+		t.Fatalf("err = %v, want ErrProviderNotFound", err)
+	}
+}
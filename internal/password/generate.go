@@ -0,0 +1,66 @@
+package password
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const (
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	lowerChars  = "abcdefghijkmnopqrstuvwxyz"
+	digitChars  = "23456789"
+	symbolChars = "!@#$%^&*-_=+?"
+)
+
+// minGeneratedLength is the floor for generated passwords, even when the policy allows shorter ones.
+const minGeneratedLength = 16
+
+// Generate returns a random password that satisfies p. Look-alike characters
+// (0/O, 1/l/I) are left out so temporary passwords can be read out loud.
+func (p Policy) Generate() (string, error) {
+	n := p.MinLength
+	if n < minGeneratedLength {
+		n = minGeneratedLength
+	}
+	if max := p.maxLength(); n > max {
+		n = max
+	}
+	// One character from every class, so every Require* rule holds
+	// regardless of what the random fill produces.
+	classes := []string{upperChars, lowerChars, digitChars, symbolChars}
+	all := upperChars + lowerChars + digitChars + symbolChars
+
+	out := make([]byte, 0, n)
+	for _, set := range classes {
+		c, err := pick(set)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+	for len(out) < n {
+		c, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+	// Fisher–Yates so the guaranteed characters are not always up front.
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("password: random: %w", err)
+		}
+		out[i], out[j.Int64()] = out[j.Int64()], out[i]
+	}
+	return string(out), nil
+}
+
+func pick(set string) (byte, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, fmt.Errorf("password: random: %w", err)
+	}
+	return set[i.Int64()], nil
+}
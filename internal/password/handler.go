@@ -0,0 +1,33 @@
+package password
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// Policy GET /api/v1/auth/password-policy?tenant_code=...
+// Public so login/reset pages can show the rules before submission.
+func (h *Handler) Policy(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.PolicyFor(c.Query("tenant_code")))
+}
+
+// WritePolicyError answers 422 with the failed rules when err is a *PolicyError
+// and reports whether it did.
+func WritePolicyError(c *gin.Context, err error) bool {
+	var pe *PolicyError
+	if !errors.As(err, &pe) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "password does not meet policy", "violations": pe.Violations})
+	return true
+}
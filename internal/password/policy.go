@@ -0,0 +1,118 @@
+// Package password validates passwords against the policy configured in
+// hyadmin_settings (auth.password.*), with per-tenant overrides.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = loadDenylist(commonPasswordsFile)
+
+// MaxLength is the longest password in bytes: bcrypt rejects longer input.
+const MaxLength = 72
+
+// Policy is the set of rules a password must satisfy.
+type Policy struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"` // bytes; at most MaxLength
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DenyCommon       bool `json:"deny_common"`
+}
+
+// Violation describes one failed rule.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password failed.
+type PolicyError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.Rule
+	}
+	return "password: policy violated: " + strings.Join(rules, ", ")
+}
+
+// Check returns a *PolicyError if password breaks any rule, nil otherwise.
+// The password may never equal the username, regardless of policy.
+func (p Policy) Check(password, username string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var vs []Violation
+	if n := len([]rune(password)); n < p.MinLength {
+		vs = append(vs, Violation{"min_length", fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+	if max := p.maxLength(); len(password) > max {
+		vs = append(vs, Violation{"max_length", fmt.Sprintf("must be at most %d bytes", max)})
+	}
+	if p.RequireUppercase && !upper {
+		vs = append(vs, Violation{"require_uppercase", "must contain an uppercase letter"})
+	}
+	if p.RequireLowercase && !lower {
+		vs = append(vs, Violation{"require_lowercase", "must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !digit {
+		vs = append(vs, Violation{"require_digit", "must contain a digit"})
+	}
+	if p.RequireSymbol && !symbol {
+		vs = append(vs, Violation{"require_symbol", "must contain a symbol"})
+	}
+	if p.DenyCommon && commonPasswords[strings.ToLower(password)] {
+		vs = append(vs, Violation{"deny_common", "is too common"})
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		vs = append(vs, Violation{"not_username", "must not equal the username"})
+	}
+	if len(vs) > 0 {
+		return &PolicyError{Violations: vs}
+	}
+	return nil
+}
+
+// maxLength is p.MaxLength capped at MaxLength; zero means MaxLength.
+func (p Policy) maxLength() int {
+	if p.MaxLength <= 0 || p.MaxLength > MaxLength {
+		return MaxLength
+	}
+	return p.MaxLength
+}
+
+func loadDenylist(content string) map[string]bool {
+	m := make(map[string]bool)
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m[strings.ToLower(line)] = true
+	}
+	return m
+}
@@ -0,0 +1,97 @@
+package password
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	strict := Policy{MinLength: 10, RequireUppercase: true, RequireLowercase: true, RequireDigit: true, RequireSymbol: true, DenyCommon: true}
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		username string
+		want     []string // violated rules, in order
+	}{
+		{"empty policy", Policy{}, "x", "", nil},
+		{"strict ok", strict, "Correct-Horse7", "alice", nil},
+		{"too short", strict, "Sh0rt!", "", []string{"min_length"}},
+		{"min length in characters", Policy{MinLength: 4}, "äöüß", "", nil},
+		{"exactly max", Policy{}, strings.Repeat("a", MaxLength), "", nil},
+		{"over max", Policy{}, strings.Repeat("a", MaxLength+1), "", []string{"max_length"}},
+		{"max in bytes", Policy{}, strings.Repeat("ä", MaxLength/2+1), "", []string{"max_length"}},
+		{"lower max", Policy{MaxLength: 8}, "123456789", "", []string{"max_length"}},
+		{"max above bcrypt limit", Policy{MaxLength: 200}, strings.Repeat("a", MaxLength+1), "", []string{"max_length"}},
+		{"missing classes", strict, "alllowercase", "", []string{"require_uppercase", "require_digit", "require_symbol"}},
+		{"space is a symbol", Policy{RequireSymbol: true}, "two words", "", nil},
+		{"common", Policy{DenyCommon: true}, "Football", "", []string{"deny_common"}},
+		{"common allowed", Policy{}, "football", "", nil},
+		{"username", Policy{}, "Alice", "alice", []string{"not_username"}},
+		{"no username", Policy{}, "", "", nil},
+		{"everything", strict, "monkey", "monkey", []string{"min_length", "require_uppercase", "require_digit", "require_symbol", "deny_common", "not_username"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.password, tt.username)
+			var got []string
+			var pe *PolicyError
+			if errors.As(err, &pe) {
+				for _, v := range pe.Violations {
+					got = append(got, v.Rule)
+				}
+			} else if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q) violated %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	all := Policy{RequireUppercase: true, RequireLowercase: true, RequireDigit: true, RequireSymbol: true, DenyCommon: true}
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantLen int
+	}{
+		{"floor", all, minGeneratedLength},
+		{"longer minimum", Policy{MinLength: 40, RequireDigit: true}, 40},
+		{"minimum at max", Policy{MinLength: MaxLength}, MaxLength},
+		{"minimum above max", Policy{MinLength: MaxLength + 10}, MaxLength},
+		{"lower max", Policy{MaxLength: 12}, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			for range 20 {
+				pw, err := tt.policy.Generate()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pw) != tt.wantLen {
+					t.Fatalf("Generate() = %q, length %d; want %d", pw, len(pw), tt.wantLen)
+				}
+				if strings.ContainsAny(pw, "0O1lI") {
+					t.Errorf("Generate() = %q contains a look-alike character", pw)
+				}
+				// Every class is present, so the strictest policy holds too.
+				strict := all
+				strict.MinLength, strict.MaxLength = tt.policy.MinLength, tt.policy.MaxLength
+				if tt.policy.MinLength <= tt.policy.maxLength() {
+					if err := strict.Check(pw, ""); err != nil {
+						t.Errorf("Generate() = %q: %v", pw, err)
+					}
+				}
+				seen[pw] = true
+			}
+			if len(seen) < 20 {
+				t.Errorf("20 generated passwords had %d distinct values", len(seen))
+			}
+		})
+	}
+}
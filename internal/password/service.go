@@ -0,0 +1,60 @@
+package password
+
+import (
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+// Service resolves the effective policy for a tenant.
+type Service struct {
+	settings *setting.Service
+}
+
+func NewService(settings *setting.Service) *Service {
+	return &Service{settings: settings}
+}
+
+// PolicyFor reads auth.password.* with tenant overrides applied.
+// A min_length above MaxLength is clamped to it; otherwise no password,
+// generated ones included, could satisfy the policy.
+func (s *Service) PolicyFor(tenantCode string) Policy {
+	minLength := s.settings.GetTenantInt(tenantCode, "auth.password.min_length", 8)
+	if minLength > MaxLength {
+		minLength = MaxLength
+	}
+	return Policy{
+		MinLength:        minLength,
+		MaxLength:        MaxLength,
+		RequireUppercase: s.settings.GetTenantBool(tenantCode, "auth.password.require_uppercase", true),
+		RequireLowercase: s.settings.GetTenantBool(tenantCode, "auth.password.require_lowercase", false),
+		RequireDigit:     s.settings.GetTenantBool(tenantCode, "auth.password.require_digit", false),
+		RequireSymbol:    s.settings.GetTenantBool(tenantCode, "auth.password.require_symbol", false),
+		DenyCommon:       s.settings.GetTenantBool(tenantCode, "auth.password.deny_common", true),
+	}
+}
+
+// Validate checks a password for a user of tenantCode.
+func (s *Service) Validate(tenantCode, username, password string) error {
+	return s.PolicyFor(tenantCode).Check(password, username)
+}
+
+// HistoryCount is how many previous passwords may not be reused (0 = no check).
+func (s *Service) HistoryCount(tenantCode string) int {
+	return s.settings.GetTenantInt(tenantCode, "auth.password.history_count", 5)
+}
+
+// Expired reports whether a password set at changedAt is older than the
+// tenant's auth.password.max_age_days (0 = never expires).
+func (s *Service) Expired(tenantCode string, changedAt *time.Time) bool {
+	days := s.settings.GetTenantInt(tenantCode, "auth.password.max_age_days", 0)
+	if days <= 0 || changedAt == nil {
+		return false
+	}
+	return time.Since(*changedAt) > time.Duration(days)*24*time.Hour
+}
+
+// Generate returns a random password that satisfies the tenant's policy.
+func (s *Service) Generate(tenantCode string) (string, error) {
+	return s.PolicyFor(tenantCode).Generate()
+}
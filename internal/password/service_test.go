@@ -0,0 +1,54 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+func TestPolicyForClampsMinLength(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection would get its own :memory: database
+	if err := db.AutoMigrate(&setting.Setting{}, &setting.TenantSetting{}); err != nil {
+		t.Fatal(err)
+	}
+	settings := setting.NewService(setting.NewRepository(db))
+	svc := NewService(settings)
+
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 8}, // not an integer: the default
+		{"12", 12},
+		{"72", MaxLength},
+		{"73", MaxLength},
+		{"1000", MaxLength},
+	}
+	for _, tt := range tests {
+		if err := settings.PutTenant("acme", "auth.password.min_length", tt.value, "test"); err != nil {
+			t.Fatal(err)
+		}
+		p := svc.PolicyFor("acme")
+		if p.MinLength != tt.want {
+			t.Errorf("min_length %q: MinLength = %d, want %d", tt.value, p.MinLength, tt.want)
+		}
+		pw, err := p.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Check(pw, ""); err != nil {
+			t.Errorf("min_length %q: generated password fails the policy: %v", tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package passwordreset
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/password"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// Forgot POST /api/v1/auth/password/forgot
+// Always answers 202 so the response cannot be used to probe for accounts.
+func (h *Handler) Forgot(c *gin.Context) {
+	var req ForgotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.svc.Forgot(c.Request.Context(), &req, c.ClientIP())
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset link has been sent"})
+}
+
+// Reset POST /api/v1/auth/password/reset
+func (h *Handler) Reset(c *gin.Context) {
+	var req ResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Reset(&req, c.ClientIP()); err != nil {
+		if password.WritePolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, adminuser.ErrPasswordReused) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+}
@@ -0,0 +1,27 @@
+package passwordreset
+
+import "time"
+
+func (Token) TableName() string { return "hyadmin_password_reset_tokens" }
+
+// Token is a single-use password reset token. Only the SHA-256 hash of the
+// emailed secret is stored.
+type Token struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	IP        string     `json:"ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ForgotRequest struct {
+	TenantCode string `json:"tenant_code" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+}
+
+type ResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -0,0 +1,53 @@
+package passwordreset
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(t *Token) error {
+	return r.db.Create(t).Error
+}
+
+func (r *Repository) FindByHash(hash string) (*Token, error) {
+	var t Token
+	err := r.db.Where("token_hash = ?", hash).First(&t).Error
+	return &t, err
+}
+
+// Consume marks an unused, unexpired token as used. It reports false when the
+// token was already used or has expired, so concurrent redemptions cannot both win.
+func (r *Repository) Consume(id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&Token{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+// InvalidateForUser marks every outstanding token of the user as used.
+func (r *Repository) InvalidateForUser(userID uint, now time.Time) error {
+	return r.db.Model(&Token{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", now).Error
+}
+
+// Release makes a consumed token usable again, e.g. when the new password was rejected.
+func (r *Repository) Release(id uint) error {
+	return r.db.Model(&Token{}).Where("id = ?", id).Update("used_at", nil).Error
+}
+
+// LatestForUser returns the newest token issued to the user, if any.
+func (r *Repository) LatestForUser(userID uint) (*Token, error) {
+	var t Token
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&t).Error
+	return &t, err
+}
@@ -0,0 +1,153 @@
+package passwordreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"go.uber.org/zap"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mail"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+var ErrInvalidToken = errors.New("passwordreset: invalid or expired token")
+
+type Service struct {
+	repo     *Repository
+	users    *adminuser.Service
+	mailer   *mail.Service
+	guard    *lockout.Service
+	settings *setting.Service
+	audit    *auditlog.Service
+	log      *zap.Logger
+}
+
+func NewService(repo *Repository, users *adminuser.Service, mailer *mail.Service, guard *lockout.Service,
+	settings *setting.Service, audit *auditlog.Service, log *zap.Logger) *Service {
+	return &Service{repo: repo, users: users, mailer: mailer, guard: guard, settings: settings, audit: audit, log: log}
+}
+
+// Forgot emails a reset link to the account's address. It deliberately returns
+// nothing about whether the account exists; only internal failures are logged.
+// The work runs in the background, so the caller's response time does not
+// depend on whether an account was found and mailed either.
+func (s *Service) Forgot(ctx context.Context, req *ForgotRequest, ip string) {
+	ctx = context.WithoutCancel(ctx)
+	r := *req
+	go s.forgot(ctx, &r, ip)
+}
+
+func (s *Service) forgot(ctx context.Context, req *ForgotRequest, ip string) {
+	u, err := s.users.GetByUsername(req.TenantCode, req.Username)
+	if err != nil || !u.Enabled || u.Provider != "local" {
+		return
+	}
+	dto, err := s.users.GetByID(u.ID)
+	if err != nil || dto.Email == "" {
+		return
+	}
+	now := time.Now()
+	cooldown := time.Duration(s.settings.GetInt("auth.password.reset_cooldown_seconds", 60)) * time.Second
+	if last, err := s.repo.LatestForUser(u.ID); err == nil && now.Sub(last.CreatedAt) < cooldown {
+		return
+	}
+	if err := s.repo.InvalidateForUser(u.ID, now); err != nil {
+		s.log.Error("password reset: invalidate tokens", zap.Error(err))
+		return
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		s.log.Error("password reset: generate token", zap.Error(err))
+		return
+	}
+	ttl := time.Duration(s.settings.GetInt("auth.password.reset_expire_minutes", 30)) * time.Minute
+	t := &Token{UserID: u.ID, TokenHash: hashToken(secret), IP: ip, ExpiresAt: now.Add(ttl)}
+	if err := s.repo.Create(t); err != nil {
+		s.log.Error("password reset: store token", zap.Error(err))
+		return
+	}
+
+	link := strings.ReplaceAll(s.settings.GetString("auth.password.reset_url", "/reset-password?token={token}"), "{token}", secret)
+	platform := s.settings.GetString("ui.platform_name", "HySP Admin")
+	msg := mail.Message{
+		To:      dto.Email,
+		Subject: fmt.Sprintf("%s password reset", platform),
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your %s account.\n"+
+			"Open the link below within %d minutes to choose a new password:\n\n%s\n\n"+
+			"If you did not request this, you can ignore this email.\n",
+			dto.DisplayName, platform, int(ttl.Minutes()), link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.log.Error("password reset: send mail", zap.Uint("user_id", u.ID), zap.Error(err))
+		return
+	}
+	s.record(u, "PASSWORD_RESET_REQUESTED", ip)
+}
+
+// Reset redeems a token and sets the new password. A rejected password
+// (policy or history) leaves the token usable until it expires.
+func (s *Service) Reset(req *ResetRequest, ip string) error {
+	t, err := s.repo.FindByHash(hashToken(req.Token))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	ok, err := s.repo.Consume(t.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidToken
+	}
+	if err := s.users.ResetPassword(t.UserID, req.NewPassword, false, "password_reset"); err != nil {
+		_ = s.repo.Release(t.ID)
+		return err
+	}
+	if err := s.repo.InvalidateForUser(t.UserID, time.Now()); err != nil {
+		return err
+	}
+	u, err := s.users.GetByID(t.UserID)
+	if err != nil {
+		return err
+	}
+	// Proving control of the mailbox also lifts any login lockout.
+	_ = s.guard.UnlockUser(u.TenantCode, u.Username)
+	s.record(&adminuser.AdminUser{ID: u.ID, TenantCode: u.TenantCode, Username: u.Username}, "PASSWORD_RESET", ip)
+	return nil
+}
+
+func (s *Service) record(u *adminuser.AdminUser, action, ip string) {
+	s.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: u.TenantCode,
+		UserID:     u.ID,
+		Username:   u.Username,
+		Action:     action,
+		Resource:   "auth",
+		ResourceID: fmt.Sprintf("%d", u.ID),
+		IP:         ip,
+	})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("passwordreset: random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/robert7528/hycore/middleware"
+
+	localauth "github.com/hysp/hyadmin-api/internal/auth"
 )
 
 type Handler struct {
@@ -31,7 +33,7 @@ func (h *Handler) ListForUser(c *gin.Context) {
 	}
 	// Get permission codes from context (set by auth middleware)
 	codes := middleware.GetPermissionCodes(c)
-	modules, err := h.svc.ListForUser(codes)
+	modules, err := h.svc.ListForUser(codes, localauth.GetDeniedCodes(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -43,13 +43,14 @@ func (s *Service) List() ([]PlatformModule, error) {
 
 // ListForUser returns modules visible to the user based on their permission codes.
 // A module is visible if the user has at least one menu-type permission for a feature in it.
-func (s *Service) ListForUser(permCodes []string) ([]PlatformModule, error) {
+// Codes may be patterns such as "cert.*"; denied codes and patterns win over them.
+func (s *Service) ListForUser(permCodes, denied []string) ([]PlatformModule, error) {
 	if len(permCodes) == 0 {
 		return []PlatformModule{}, nil
 	}
 
-	// Find permissions matching user's codes
-	perms, err := s.permRepo.FindByCodes(permCodes)
+	// Expand the user's codes against the catalog
+	perms, err := s.permRepo.ListAll()
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +58,7 @@ func (s *Service) ListForUser(permCodes []string) ([]PlatformModule, error) {
 	// Collect feature IDs that have menu-type permissions
 	featureIDs := make(map[uint]struct{})
 	for _, p := range perms {
-		if p.Type == "menu" {
+		if p.Type == "menu" && permission.MatchAny(permCodes, p.Code) && !permission.MatchAny(denied, p.Code) {
 			featureIDs[p.FeatureID] = struct{}{}
 		}
 	}
@@ -0,0 +1,72 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wildcard matches any run of characters in a pattern, dots included, so
+// "cert.*" covers every code of the cert module, "*.view" every view code
+// and "*" alone every code.
+const Wildcard = "*"
+
+// IsPattern reports whether code contains a wildcard.
+func IsPattern(code string) bool {
+	return strings.Contains(code, Wildcard)
+}
+
+// Match reports whether code is covered by pattern. A pattern without
+// wildcards only matches itself.
+func Match(pattern, code string) bool {
+	if !IsPattern(pattern) {
+		return pattern == code
+	}
+	parts := strings.Split(pattern, Wildcard)
+	if !strings.HasPrefix(code, parts[0]) {
+		return false
+	}
+	rest := code[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return len(rest) >= len(parts[last]) && strings.HasSuffix(rest, parts[last])
+}
+
+// MatchAny reports whether any of patterns covers code.
+func MatchAny(patterns []string, code string) bool {
+	for _, p := range patterns {
+		if Match(p, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns the codes of catalog covered by allow and not by deny, in
+// catalog order. Denies are applied after expansion, so a deny pattern
+// removes every code it covers, whichever allow granted it.
+func Expand(catalog, allow, deny []string) []string {
+	out := make([]string, 0)
+	for _, code := range catalog {
+		if MatchAny(allow, code) && !MatchAny(deny, code) {
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+// MatchFunc exposes Match to Casbin matchers as permMatch(r.obj, p.obj);
+// register it with Enforcer.AddFunction("permMatch", MatchFunc).
+func MatchFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return false, fmt.Errorf("permMatch: want 2 arguments, got %d", len(args))
+	}
+	code, _ := args[0].(string)
+	pattern, _ := args[1].(string)
+	return Match(pattern, code), nil
+}
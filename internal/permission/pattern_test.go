@@ -0,0 +1,170 @@
+package permission
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, code string
+		want          bool
+	}{
+		{"user.view", "user.view", true},
+		{"user.view", "user.edit", false},
+		{"user.view", "user.view.all", false},
+		{"*", "user.view", true},
+		{"*", "", true},
+		{"user.*", "user.view", true},
+		{"user.*", "user.view.all", true},
+		{"user.*", "user.", true},
+		{"user.*", "user", false},
+		{"user.*", "users.view", false},
+		{"*.view", "user.view", true},
+		{"*.view", "cert.key.view", true},
+		{"*.view", "user.viewer", false},
+		{"*.view", "view", false},
+		{"cert.*.view", "cert.key.view", true},
+		{"cert.*.view", "cert.view", false},
+		{"cert.*.view", "cert.a.b.view", true},
+		{"a*a", "a", false},
+		{"a*a", "aa", true},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "acb", false},
+		{"**", "anything", true},
+		{"", "", true},
+		{"", "user.view", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.code); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	tests := map[string]bool{"user.view": false, "user.*": true, "*": true, "": false}
+	for code, want := range tests {
+		if got := IsPattern(code); got != want {
+			t.Errorf("IsPattern(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		code     string
+		want     bool
+	}{
+		{nil, "user.view", false},
+		{[]string{"role.*", "user.view"}, "user.view", true},
+		{[]string{"role.*", "user.view"}, "role.edit", true},
+		{[]string{"role.*", "user.view"}, "user.edit", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAny(tt.patterns, tt.code); got != tt.want {
+			t.Errorf("MatchAny(%q, %q) = %v, want %v", tt.patterns, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	catalog := []string{"user.view", "user.edit", "user.delete", "role.view", "role.edit", "cert.view"}
+	tests := []struct {
+		name        string
+		allow, deny []string
+		want        []string
+	}{
+		{"nothing", nil, nil, []string{}},
+		{"exact", []string{"role.edit"}, nil, []string{"role.edit"}},
+		{"unknown code", []string{"nope.view"}, nil, []string{}},
+		{"module", []string{"user.*"}, nil, []string{"user.view", "user.edit", "user.delete"}},
+		{"action", []string{"*.view"}, nil, []string{"user.view", "role.view", "cert.view"}},
+		{"all", []string{"*"}, nil, catalog},
+		{"deny exact", []string{"user.*"}, []string{"user.delete"}, []string{"user.view", "user.edit"}},
+		{"deny pattern", []string{"*"}, []string{"*.edit", "*.delete"}, []string{"user.view", "role.view", "cert.view"}},
+		{"deny beats exact allow", []string{"role.edit"}, []string{"role.*"}, []string{}},
+		{"deny all", []string{"*"}, []string{"*"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(catalog, tt.allow, tt.deny); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand(%q, %q) = %q, want %q", tt.allow, tt.deny, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFunc(t *testing.T) {
+	got, err := MatchFunc("user.view", "user.*")
+	if err != nil || got != true {
+		t.Errorf("MatchFunc(user.view, user.*) = %v, %v; want true", got, err)
+	}
+	got, err = MatchFunc("role.view", "user.*")
+	if err != nil || got != false {
+		t.Errorf("MatchFunc(role.view, user.*) = %v, %v; want false", got, err)
+	}
+	if _, err := MatchFunc("user.view"); err == nil {
+		t.Error("MatchFunc with one argument: want an error")
+	}
+}
+
+// TestModel runs the patterns through the Casbin model the app loads.
+func TestModel(t *testing.T) {
+	e, err := casbin.NewEnforcer("../../configs/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddFunction("permMatch", MatchFunc)
+	policies := [][]string{
+		{"role:1", "acme", "user.*", "access", "allow"},
+		{"role:1", "acme", "user.delete", "access", "deny"},
+		{"role:2", "acme", "*.view", "access", "allow"},
+		{"role:3", "acme", "*", "access", "allow"},
+		{"role:3", "acme", "cert.*", "access", "deny"},
+	}
+	for _, p := range policies {
+		if _, err := e.AddPolicy(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, g := range [][]string{
+		{"user:1", "role:1", "acme"},
+		{"user:2", "role:2", "acme"},
+		{"user:3", "role:3", "acme"},
+		{"user:4", "role:1", "acme"},
+		{"user:4", "role:2", "acme"},
+	} {
+		if _, err := e.AddGroupingPolicy(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sub, dom, obj string
+		want          bool
+	}{
+		{"user:1", "acme", "user.view", true},
+		{"user:1", "acme", "user.delete", false}, // deny overrides the user.* allow
+		{"user:1", "acme", "role.view", false},
+		{"user:1", "other", "user.view", false}, // roles only apply in their tenant
+		{"user:2", "acme", "cert.view", true},
+		{"user:2", "acme", "cert.edit", false},
+		{"user:3", "acme", "role.edit", true},
+		{"user:3", "acme", "cert.view", false},
+		{"user:4", "acme", "user.delete", false}, // a deny from one role beats allows from another
+		{"user:4", "acme", "role.view", true},
+	}
+	for _, tt := range tests {
+		got, err := e.Enforce(tt.sub, tt.dom, tt.obj, "access")
+		if err != nil {
+			t.Fatalf("Enforce(%s, %s, %s): %v", tt.sub, tt.dom, tt.obj, err)
+		}
+		if got != tt.want {
+			t.Errorf("Enforce(%s, %s, %s) = %v, want %v", tt.sub, tt.dom, tt.obj, got, tt.want)
+		}
+	}
+}
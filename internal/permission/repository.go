@@ -32,6 +32,20 @@ func (r *Repository) FindByCodes(codes []string) ([]Permission, error) {
 	return perms, err
 }
 
+// ListCodes returns every permission code in the catalog.
+func (r *Repository) ListCodes() ([]string, error) {
+	var codes []string
+	err := r.db.Model(&Permission{}).Order("code").Pluck("code", &codes).Error
+	return codes, err
+}
+
+// ListAll returns every permission in the catalog.
+func (r *Repository) ListAll() ([]Permission, error) {
+	var perms []Permission
+	err := r.db.Order("sort_order, id").Find(&perms).Error
+	return perms, err
+}
+
 func (r *Repository) Update(id uint, updates map[string]interface{}) error {
 	return r.db.Model(&Permission{}).Where("id = ?", id).Updates(updates).Error
 }
@@ -1,6 +1,11 @@
 package permission
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownCode = errors.New("permission: code or pattern matches no permission")
 
 type Service struct {
 	repo *Repository
@@ -62,6 +67,41 @@ func (s *Service) BatchCreate(req *BatchCreateRequest) ([]Permission, error) {
 	return result, nil
 }
 
+// Validate checks that every code is in the catalog and every pattern covers
+// at least one code of it. The lone "*" is always valid.
+func (s *Service) Validate(codes []string) error {
+	catalog, err := s.repo.ListCodes()
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		if code == Wildcard {
+			continue
+		}
+		found := false
+		for _, c := range catalog {
+			if Match(code, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownCode, code)
+		}
+	}
+	return nil
+}
+
+// Expand resolves allow and deny codes and patterns to the concrete catalog
+// codes they grant; see Expand.
+func (s *Service) Expand(allow, deny []string) ([]string, error) {
+	catalog, err := s.repo.ListCodes()
+	if err != nil {
+		return nil, err
+	}
+	return Expand(catalog, allow, deny), nil
+}
+
 func (s *Service) GetByID(id uint) (*Permission, error) {
 	return s.repo.FindByID(id)
 }
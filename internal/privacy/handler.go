@@ -0,0 +1,115 @@
+package privacy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"github.com/robert7528/hycore/middleware"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+)
+
+type Handler struct {
+	svc   *Service
+	audit *auditlog.Service
+}
+
+func NewHandler(svc *Service, audit *auditlog.Service) *Handler {
+	return &Handler{svc: svc, audit: audit}
+}
+
+// Export GET /api/v1/admin/users/:id/personal-data
+// Returns everything stored about the user as a JSON download.
+func (h *Handler) Export(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	exp, err := h.svc.Export(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.record(c, "PERSONAL_DATA_EXPORT", uint(id), map[string]interface{}{
+		"target_user_id":     id,
+		"target_tenant_code": exp.User.TenantCode,
+		"sessions":           len(exp.Sessions),
+		"audit_logs":         len(exp.AuditLogs),
+	})
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-personal-data.json"`, id))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, exp)
+}
+
+// Erase POST /api/v1/admin/users/:id/erase
+// Irreversibly wipes the user's personal data; see Service.Erase.
+func (h *Handler) Erase(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req EraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var actorID uint
+	if claims := middleware.GetClaims(c); claims != nil {
+		actorID = claims.UserID
+	}
+	res, err := h.svc.Erase(uint(id), actorID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, ErrConfirmMismatch), errors.Is(err, ErrEraseSelf):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrAlreadyErased):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	// The entry names the user only by ID and pseudonym.
+	h.record(c, "PERSONAL_DATA_ERASE", uint(id), map[string]interface{}{
+		"target_user_id":       id,
+		"pseudonym":            res.Pseudonym,
+		"roles_removed":        res.RolesRemoved,
+		"sessions_wiped":       res.SessionsWiped,
+		"tokens_revoked":       res.TokensRevoked,
+		"audit_logs_rewritten": res.AuditLogsRewritten,
+	})
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) record(c *gin.Context, action string, targetID uint, d map[string]interface{}) {
+	actor := middleware.GetClaims(c)
+	if actor == nil {
+		return
+	}
+	detail, _ := json.Marshal(d)
+	h.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: actor.TenantCode,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		Action:     action,
+		Resource:   "users",
+		ResourceID: strconv.FormatUint(uint64(targetID), 10),
+		Detail:     string(detail),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+}
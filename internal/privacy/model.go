@@ -0,0 +1,47 @@
+package privacy
+
+import (
+	"fmt"
+	"time"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+// Export is the data subject access export of one admin user.
+type Export struct {
+	GeneratedAt time.Time               `json:"generated_at"`
+	User        *adminuser.AdminUserDTO `json:"user"`
+	Roles       []RoleRef               `json:"roles"`
+	Sessions    []session.Session       `json:"sessions"`
+	AuditLogs   []coreauditlog.AuditLog `json:"audit_logs"` // entries the user authored
+}
+
+type RoleRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// EraseRequest must repeat the username, guarding against erasing the wrong ID.
+type EraseRequest struct {
+	ConfirmUsername string `json:"confirm_username" binding:"required"`
+}
+
+// Erasure reports what an erasure changed.
+type Erasure struct {
+	UserID             uint   `json:"user_id"`
+	Pseudonym          string `json:"pseudonym"`
+	RolesRemoved       int    `json:"roles_removed"`
+	SessionsWiped      int64  `json:"sessions_wiped"`
+	TokensRevoked      int64  `json:"tokens_revoked"`
+	AuditLogsRewritten int64  `json:"audit_logs_rewritten"`
+}
+
+// Pseudonym replaces the username of an erased user everywhere it is kept.
+// It stays unique per user, so audit trails remain attributable to one
+// (anonymous) account.
+func Pseudonym(userID uint) string {
+	return fmt.Sprintf("erased-%d", userID)
+}
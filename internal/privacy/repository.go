@@ -0,0 +1,96 @@
+package privacy
+
+import (
+	"time"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/apitoken"
+	"github.com/hysp/hyadmin-api/internal/invitation"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mfa"
+	"github.com/hysp/hyadmin-api/internal/passwordreset"
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/session"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Sessions(userID uint) ([]session.Session, error) {
+	var list []session.Session
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *Repository) AuditLogs(userID uint) ([]coreauditlog.AuditLog, error) {
+	var logs []coreauditlog.AuditLog
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&logs).Error
+	return logs, err
+}
+
+// Erase wipes the user's PII in one transaction: the user row keeps only
+// its ID, tenant and timestamps, and is soft-deleted; every copy of the
+// username becomes pseudonym; secrets and client details tied to the user
+// are deleted or blanked.
+func (r *Repository) Erase(userID uint, tenantCode, username, pseudonym string, now time.Time, res *Erasure) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&adminuser.AdminUser{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"username":             pseudonym,
+			"display_name":         "",
+			"email":                "",
+			"email_bidx":           "",
+			"search_bidx":          "",
+			"password_hash":        "",
+			"provider_id":          "",
+			"totp_secret":          "",
+			"mfa_enabled":          false,
+			"enabled":              false,
+			"must_change_password": false,
+			"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error; err != nil {
+			return err
+		}
+
+		q := tx.Model(&session.Session{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"username": pseudonym, "ip": "", "user_agent": ""})
+		if q.Error != nil {
+			return q.Error
+		}
+		res.SessionsWiped = q.RowsAffected
+		if err := tx.Model(&session.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": "erased"}).Error; err != nil {
+			return err
+		}
+
+		q = tx.Model(&apitoken.Token{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now)
+		if q.Error != nil {
+			return q.Error
+		}
+		res.TokensRevoked = q.RowsAffected
+		if err := tx.Model(&apitoken.Token{}).Where("user_id = ?", userID).Update("last_used_ip", "").Error; err != nil {
+			return err
+		}
+
+		q = tx.Model(&coreauditlog.AuditLog{}).Where("user_id = ?", userID).Update("username", pseudonym)
+		if q.Error != nil {
+			return q.Error
+		}
+		res.AuditLogsRewritten = q.RowsAffected
+
+		// Scheduled role windows too, or the expiry job would grant them later.
+		for _, m := range []interface{}{&adminuser.PasswordHistory{}, &mfa.RecoveryCode{}, &passwordreset.Token{}, &invitation.Invitation{}, &role.UserRole{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("key = ?", lockout.UserKey(tenantCode, username)).Delete(&lockout.Throttle{}).Error
+	})
+}
@@ -0,0 +1,103 @@
+// Package privacy implements data subject requests for admin users: a full
+// export of what is stored about a user, and erasure of their personal data.
+//
+// PII is encrypted with the platform-wide Tink keyset, so there is no
+// per-user key to destroy; erasure wipes the ciphertexts instead. Rows are
+// kept under a pseudonym so audit trails stay consistent.
+package privacy
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/role"
+)
+
+var (
+	ErrConfirmMismatch = errors.New("privacy: confirm_username does not match the user")
+	ErrEraseSelf       = errors.New("privacy: you cannot erase your own account")
+	ErrAlreadyErased   = errors.New("privacy: user has already been erased")
+)
+
+type Service struct {
+	repo  *Repository
+	users *adminuser.Service
+	roles *role.Service
+}
+
+func NewService(repo *Repository, users *adminuser.Service, roles *role.Service) *Service {
+	return &Service{repo: repo, users: users, roles: roles}
+}
+
+// Export collects the user record, roles, sessions and the audit entries the
+// user authored. Soft-deleted users are included, since their PII is kept.
+func (s *Service) Export(userID uint) (*Export, error) {
+	u, err := s.users.GetByIDUnscoped(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roleRefs(u.TenantCode, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.repo.Sessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.repo.AuditLogs(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Export{GeneratedAt: time.Now(), User: u, Roles: roles, Sessions: sessions, AuditLogs: logs}, nil
+}
+
+// Erase removes the user's role assignments and wipes their personal data,
+// see Repository.Erase. actorID is the admin performing it.
+func (s *Service) Erase(userID, actorID uint, req *EraseRequest) (*Erasure, error) {
+	if userID == actorID {
+		return nil, ErrEraseSelf
+	}
+	u, err := s.users.GetByIDUnscoped(userID)
+	if err != nil {
+		return nil, err
+	}
+	pseudonym := Pseudonym(userID)
+	if u.Username == pseudonym {
+		return nil, ErrAlreadyErased
+	}
+	if req.ConfirmUsername != u.Username {
+		return nil, ErrConfirmMismatch
+	}
+	res := &Erasure{UserID: userID, Pseudonym: pseudonym}
+	roleIDs, err := s.roles.GetRolesForUser(u.TenantCode, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Casbin first: if the wipe fails afterwards the user is still
+	// recognisable and the erasure can simply be repeated.
+	if err := s.roles.AssignRolesToUser(u.TenantCode, userID, nil); err != nil {
+		return nil, err
+	}
+	res.RolesRemoved = len(roleIDs)
+	if err := s.repo.Erase(userID, u.TenantCode, u.Username, pseudonym, time.Now(), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) roleRefs(tenantCode string, userID uint) ([]RoleRef, error) {
+	ids, err := s.roles.GetRolesForUser(tenantCode, userID)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]RoleRef, 0, len(ids))
+	for _, id := range ids {
+		ref := RoleRef{ID: id}
+		if r, err := s.roles.GetByID(id); err == nil {
+			ref.Name = r.Name
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
@@ -0,0 +1,49 @@
+package provisioning
+
+import "time"
+
+func (RoleMapping) TableName() string { return "hyadmin_role_mappings" }
+func (MappedRole) TableName() string  { return "hyadmin_mapped_roles" }
+
+// RoleMapping grants RoleID to users of a tenant whose identity claim Claim
+// contains Value (e.g. claim "groups", value "hysp-admins"). Provider is an
+// external provider name, or "*" for all of them.
+type RoleMapping struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TenantCode string    `gorm:"index;not null" json:"tenant_code"`
+	Provider   string    `gorm:"not null;default:'*'" json:"provider"`
+	Claim      string    `gorm:"not null" json:"claim"`
+	Value      string    `gorm:"not null" json:"value"`
+	RoleID     uint      `gorm:"not null" json:"role_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MappedRole records that provisioning, not an administrator, granted RoleID
+// to UserID. Only these grants are revoked when the mapping no longer applies,
+// so removing or editing a rule still takes the role away on the next login.
+type MappedRole struct {
+	UserID     uint      `gorm:"primaryKey" json:"user_id"`
+	RoleID     uint      `gorm:"primaryKey" json:"role_id"`
+	TenantCode string    `gorm:"index;not null" json:"tenant_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Identity is what an external provider asserted about a user.
+// Claims holds the raw claims (ID token claims, LDAP attributes) that
+// role mappings are evaluated against.
+type Identity struct {
+	TenantCode  string
+	Provider    string
+	Subject     string
+	Username    string
+	DisplayName string
+	Email       string
+	Claims      map[string]interface{}
+}
+
+type CreateMappingRequest struct {
+	Provider string `json:"provider"` // default "*"
+	Claim    string `json:"claim" binding:"required"`
+	Value    string `json:"value" binding:"required"`
+	RoleID   uint   `json:"role_id" binding:"required"`
+}
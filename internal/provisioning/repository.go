@@ -0,0 +1,53 @@
+package provisioning
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) List(tenantCode string) ([]RoleMapping, error) {
+	var list []RoleMapping
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("id").Find(&list).Error
+	return list, err
+}
+
+// ListForProvider returns the tenant's mappings that apply to provider.
+func (r *Repository) ListForProvider(tenantCode, provider string) ([]RoleMapping, error) {
+	var list []RoleMapping
+	err := r.db.Where("tenant_code = ? AND provider IN ?", tenantCode, []string{provider, "*"}).
+		Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *Repository) Create(m *RoleMapping) error {
+	return r.db.Create(m).Error
+}
+
+func (r *Repository) Delete(tenantCode string, id uint) (bool, error) {
+	res := r.db.Where("tenant_code = ? AND id = ?", tenantCode, id).Delete(&RoleMapping{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// MappedRoles returns the IDs of the roles provisioning granted to the user.
+func (r *Repository) MappedRoles(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&MappedRole{}).Where("user_id = ?", userID).Order("role_id").Pluck("role_id", &ids).Error
+	return ids, err
+}
+
+func (r *Repository) AddMappedRole(tenantCode string, userID, roleID uint) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&MappedRole{UserID: userID, RoleID: roleID, TenantCode: tenantCode}).Error
+}
+
+func (r *Repository) RemoveMappedRole(userID, roleID uint) error {
+	return r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&MappedRole{}).Error
+}
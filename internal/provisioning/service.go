@@ -0,0 +1,239 @@
+// Package provisioning links external identities (OIDC, LDAP) to AdminUser
+// rows, creating them just in time, and keeps their roles in sync with
+// per-tenant mapping rules from IdP groups/claims to roles.
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+var (
+	ErrNotProvisioned = errors.New("provisioning: no account is linked to this identity")
+	ErrUsernameTaken  = errors.New("provisioning: username already belongs to another account")
+	ErrRoleNotFound   = errors.New("provisioning: role not found in tenant")
+	ErrNotFound       = errors.New("provisioning: mapping not found")
+)
+
+type Service struct {
+	repo     *Repository
+	users    *adminuser.Service
+	roles    *role.Service
+	settings *setting.Service
+	audit    *auditlog.Service
+}
+
+func NewService(repo *Repository, users *adminuser.Service, roles *role.Service, settings *setting.Service, audit *auditlog.Service) *Service {
+	return &Service{repo: repo, users: users, roles: roles, settings: settings, audit: audit}
+}
+
+// Resolve returns the AdminUser for an external identity. Unknown identities
+// are created when the tenant allows JIT provisioning (auth.provisioning.jit_enabled).
+// Mapped roles are resynced on every call.
+func (s *Service) Resolve(id *Identity) (*adminuser.AdminUser, error) {
+	u, err := s.users.GetByProvider(id.TenantCode, id.Provider, id.Subject)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !s.settings.GetTenantBool(id.TenantCode, "auth.provisioning.jit_enabled", true) {
+			return nil, ErrNotProvisioned
+		}
+		if u, err = s.provision(id); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	if !u.Enabled {
+		return u, adminuser.ErrUserDisabled
+	}
+	if err := s.syncRoles(u, id); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *Service) provision(id *Identity) (*adminuser.AdminUser, error) {
+	username := id.Username
+	if username == "" {
+		username = id.Subject
+	}
+	// Never attach an external identity to an existing account by username:
+	// that would let whoever controls the IdP take over local accounts.
+	if _, err := s.users.GetByUsername(id.TenantCode, username); err == nil {
+		return nil, ErrUsernameTaken
+	}
+	dto, err := s.users.Create(&adminuser.CreateUserRequest{
+		TenantCode:  id.TenantCode,
+		Username:    username,
+		DisplayName: id.DisplayName,
+		Email:       id.Email,
+		Provider:    id.Provider,
+		ProviderID:  id.Subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.record(id.TenantCode, dto.ID, dto.Username, "USER_PROVISIONED", map[string]interface{}{
+		"provider": id.Provider, "subject": id.Subject,
+	})
+	return s.users.GetByProvider(id.TenantCode, id.Provider, id.Subject)
+}
+
+// syncRoles adds the roles granted by the mapping rules and removes roles an
+// earlier sync granted that the identity no longer qualifies for, including
+// after their rule was deleted. Roles held before any rule granted them, e.g.
+// manual or time-bound grants, are left alone.
+func (s *Service) syncRoles(u *adminuser.AdminUser, id *Identity) error {
+	rules, err := s.repo.ListForProvider(id.TenantCode, id.Provider)
+	if err != nil {
+		return err
+	}
+	want := MatchRoles(rules, id.Claims)
+	have, err := s.roles.GetRolesForUser(u.TenantCode, u.ID)
+	if err != nil {
+		return err
+	}
+	mapped, err := s.repo.MappedRoles(u.ID)
+	if err != nil {
+		return err
+	}
+	add, drop := reconcile(want, have, mapped)
+	for _, rid := range add {
+		if err := s.roles.AddUserToRole(u.ID, rid); err != nil {
+			return err
+		}
+		if err := s.repo.AddMappedRole(u.TenantCode, u.ID, rid); err != nil {
+			return err
+		}
+	}
+	var removed []uint
+	for _, rid := range drop {
+		if slices.Contains(have, rid) {
+			if err := s.roles.RemoveUserFromRole(u.ID, rid); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			removed = append(removed, rid)
+		}
+		if err := s.repo.RemoveMappedRole(u.ID, rid); err != nil {
+			return err
+		}
+	}
+	if len(add) == 0 && len(removed) == 0 {
+		return nil
+	}
+	s.record(u.TenantCode, u.ID, u.Username, "ROLES_SYNCED", map[string]interface{}{
+		"provider": id.Provider, "before": have, "added": add, "removed": removed,
+	})
+	return nil
+}
+
+// reconcile returns the wanted roles the user does not hold yet, and the
+// mapped roles that are no longer wanted. A dropped role may already be gone,
+// e.g. removed by hand; its mapping record is stale either way.
+func reconcile(want, have, mapped []uint) (add, drop []uint) {
+	for _, rid := range want {
+		if !slices.Contains(have, rid) {
+			add = append(add, rid)
+		}
+	}
+	for _, rid := range mapped {
+		if !slices.Contains(want, rid) {
+			drop = append(drop, rid)
+		}
+	}
+	return add, drop
+}
+
+// MatchRoles returns the sorted, de-duplicated role IDs whose rule matches
+// claims. A claim matches when it equals Value or, for list claims, contains it.
+func MatchRoles(rules []RoleMapping, claims map[string]interface{}) []uint {
+	set := map[uint]bool{}
+	for _, r := range rules {
+		if claimContains(claims[r.Claim], r.Value) {
+			set[r.RoleID] = true
+		}
+	}
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func claimContains(v interface{}, want string) bool {
+	switch t := v.(type) {
+	case string:
+		return t == want
+	case []string:
+		for _, s := range t {
+			if s == want {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, s := range t {
+			if fmt.Sprint(s) == want {
+				return true
+			}
+		}
+	case bool, float64:
+		return fmt.Sprint(t) == want
+	}
+	return false
+}
+
+func (s *Service) List(tenantCode string) ([]RoleMapping, error) {
+	return s.repo.List(tenantCode)
+}
+
+func (s *Service) Create(tenantCode string, req *CreateMappingRequest) (*RoleMapping, error) {
+	r, err := s.roles.GetByID(req.RoleID)
+	if err != nil || r.TenantCode != tenantCode {
+		return nil, ErrRoleNotFound
+	}
+	m := &RoleMapping{TenantCode: tenantCode, Provider: req.Provider, Claim: req.Claim, Value: req.Value, RoleID: req.RoleID}
+	if m.Provider == "" {
+		m.Provider = "*"
+	}
+	if err := s.repo.Create(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *Service) Delete(tenantCode string, id uint) error {
+	ok, err := s.repo.Delete(tenantCode, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) record(tenantCode string, userID uint, username, action string, detail map[string]interface{}) {
+	b, _ := json.Marshal(detail)
+	s.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: tenantCode,
+		UserID:     userID,
+		Username:   username,
+		Action:     action,
+		Resource:   "users",
+		ResourceID: fmt.Sprintf("%d", userID),
+		Detail:     string(b),
+	})
+}
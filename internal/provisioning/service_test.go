@@ -0,0 +1,63 @@
+package provisioning
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name               string
+		want, have, mapped []uint
+		add, drop          []uint
+	}{
+		{"nothing", nil, nil, nil, nil, nil},
+		{"first login", []uint{1, 2}, nil, nil, []uint{1, 2}, nil},
+		{"in sync", []uint{1}, []uint{1}, []uint{1}, nil, nil},
+		{"manual grant kept", nil, []uint{3}, nil, nil, nil},
+		{"manual grant also mapped", []uint{3}, []uint{3}, nil, nil, nil},
+		{"left the group", []uint{1}, []uint{1, 2}, []uint{1, 2}, nil, []uint{2}},
+		{"rules deleted", nil, []uint{1, 2, 3}, []uint{1, 2}, nil, []uint{1, 2}},
+		{"removed by hand", nil, nil, []uint{4}, nil, []uint{4}},
+		{"removed by hand, still mapped", []uint{4}, nil, []uint{4}, []uint{4}, nil},
+		{"moved groups", []uint{2}, []uint{1, 3}, []uint{1}, []uint{2}, []uint{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, drop := reconcile(tt.want, tt.have, tt.mapped)
+			if !slices.Equal(add, tt.add) || !slices.Equal(drop, tt.drop) {
+				t.Errorf("reconcile(%v, %v, %v) = %v, %v; want %v, %v", tt.want, tt.have, tt.mapped, add, drop, tt.add, tt.drop)
+			}
+		})
+	}
+}
+
+func TestMatchRoles(t *testing.T) {
+	rules := []RoleMapping{
+		{Claim: "groups", Value: "admins", RoleID: 2},
+		{Claim: "groups", Value: "ops", RoleID: 1},
+		{Claim: "department", Value: "it", RoleID: 3},
+		{Claim: "email_verified", Value: "true", RoleID: 4},
+		{Claim: "groups", Value: "also-admins", RoleID: 2},
+	}
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   []uint
+	}{
+		{"no claims", nil, []uint{}},
+		{"string list", map[string]interface{}{"groups": []string{"ops", "admins"}}, []uint{1, 2}},
+		{"json list", map[string]interface{}{"groups": []interface{}{"ops"}}, []uint{1}},
+		{"single string", map[string]interface{}{"department": "it"}, []uint{3}},
+		{"bool", map[string]interface{}{"email_verified": true}, []uint{4}},
+		{"deduplicated", map[string]interface{}{"groups": []string{"admins", "also-admins"}}, []uint{2}},
+		{"no substring match", map[string]interface{}{"groups": "administrators"}, []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRoles(rules, tt.claims); !slices.Equal(got, tt.want) {
+				t.Errorf("MatchRoles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
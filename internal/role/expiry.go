@@ -0,0 +1,96 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+)
+
+// expiryInterval is how often the expiry job applies assignment windows, and
+// so how late a time-bound assignment may start or end.
+const expiryInterval = time.Minute
+
+// ExpiryJob starts and ends time-bound role assignments (see UserRole) and
+// records each change in the audit log.
+type ExpiryJob struct {
+	svc   *Service
+	audit *auditlog.Service
+	log   *zap.Logger
+}
+
+func NewExpiryJob(svc *Service, audit *auditlog.Service, log *zap.Logger) *ExpiryJob {
+	return &ExpiryJob{svc: svc, audit: audit, log: log}
+}
+
+// Run applies the windows due at now once.
+func (j *ExpiryJob) Run(now time.Time) {
+	started, ended, err := j.svc.ApplySchedules(now)
+	for _, ur := range started {
+		j.record("ROLE_ASSIGNMENT_START", ur)
+	}
+	for _, ur := range ended {
+		j.record("ROLE_ASSIGNMENT_EXPIRE", ur)
+	}
+	if err != nil {
+		j.log.Error("role assignment expiry failed", zap.Error(err))
+	}
+}
+
+func (j *ExpiryJob) record(action string, ur UserRole) {
+	detail, _ := json.Marshal(map[string]interface{}{
+		"user_id":     ur.UserID,
+		"role_id":     ur.RoleID,
+		"valid_from":  ur.ValidFrom,
+		"valid_until": ur.ValidUntil,
+		"granted_by":  ur.GrantedBy,
+	})
+	j.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: ur.TenantCode,
+		Username:   "system",
+		Action:     action,
+		Resource:   "roles",
+		ResourceID: fmt.Sprintf("%d", ur.RoleID),
+		Detail:     string(detail),
+	})
+}
+
+// StartExpiryJob runs the job every expiryInterval while the app runs. Every
+// replica runs it; an advisory lock in ApplySchedules lets one apply each round.
+func StartExpiryJob(lc fx.Lifecycle, j *ExpiryJob) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				t := time.NewTicker(expiryInterval)
+				defer t.Stop()
+				j.Run(time.Now())
+				for {
+					select {
+					case <-stop:
+						return
+					case now := <-t.C:
+						j.Run(now)
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			select {
+			case <-done:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	})
+}
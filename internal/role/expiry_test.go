@@ -0,0 +1,173 @@
+package role
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func at(d time.Duration) *time.Time {
+	t := testNow.Add(d)
+	return &t
+}
+
+func TestCheckWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, until *time.Time
+		want        error
+	}{
+		{"permanent", nil, nil, nil},
+		{"open ended", at(time.Hour), nil, nil},
+		{"until later", nil, at(time.Second), nil},
+		{"until now", nil, at(0), ErrRoleWindow},
+		{"until past", nil, at(-time.Hour), ErrRoleWindow},
+		{"scheduled", at(time.Hour), at(2 * time.Hour), nil},
+		{"started", at(-time.Hour), at(time.Hour), nil},
+		{"empty window", at(time.Hour), at(time.Hour), ErrRoleWindow},
+		{"inverted window", at(2 * time.Hour), at(time.Hour), ErrRoleWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWindow(UserAssignment{UserID: 1, ValidFrom: tt.from, ValidUntil: tt.until}, testNow)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkWindow = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// newScheduleService returns a Service over in-memory SQLite and an
+// in-memory enforcer, with users 1..9 in tenant "acme".
+func newScheduleService(t *testing.T) (*Service, *casbin.Enforcer, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection would get its own :memory: database
+	if err := db.AutoMigrate(&UserRole{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE hyadmin_users (id INTEGER PRIMARY KEY, tenant_code TEXT, deleted_at DATETIME)").Error; err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 9; id++ {
+		if err := db.Exec("INSERT INTO hyadmin_users (id, tenant_code) VALUES (?, 'acme')", id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, err := casbin.NewEnforcer("../../configs/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(NewRepository(db, e), nil), e, db
+}
+
+func TestApplySchedules(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, until *time.Time
+		active      bool // g policy already in place
+		wantStart   bool
+		wantEnd     bool
+		wantHeld    bool
+	}{
+		{"opens now", at(0), at(time.Hour), false, true, false, true},
+		{"opened earlier", at(-time.Hour), nil, false, true, false, true},
+		{"opens later", at(time.Second), nil, false, false, false, false},
+		{"closes now", at(-time.Hour), at(0), true, false, true, false},
+		{"closed earlier", nil, at(-time.Second), true, false, true, false},
+		{"closes later", at(-time.Hour), at(time.Second), true, false, false, true},
+		{"closed before it opened", at(-2 * time.Hour), at(-time.Hour), false, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, e, db := newScheduleService(t)
+			ur := UserRole{UserID: 1, RoleID: 7, TenantCode: "acme", ValidFrom: tt.from, ValidUntil: tt.until, Active: tt.active}
+			if err := db.Create(&ur).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.active {
+				if _, err := e.AddRoleForUserInDomain(UserSubject(1), roleSubject(7), "acme"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			started, ended, err := svc.applySchedules(testNow)
+			if err != nil {
+				t.Fatalf("applySchedules: %v", err)
+			}
+			if got := len(started) == 1; got != tt.wantStart {
+				t.Errorf("started = %v, want start %v", started, tt.wantStart)
+			}
+			if got := len(ended) == 1; got != tt.wantEnd {
+				t.Errorf("ended = %v, want end %v", ended, tt.wantEnd)
+			}
+			held, err := e.HasGroupingPolicy(UserSubject(1), roleSubject(7), "acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if held != tt.wantHeld {
+				t.Errorf("g policy held = %v, want %v", held, tt.wantHeld)
+			}
+			var rows int64
+			db.Model(&UserRole{}).Count(&rows)
+			if tt.wantEnd && rows != 0 {
+				t.Errorf("the window of an ended assignment was kept")
+			}
+		})
+	}
+}
+
+func TestApplySchedulesDropsUsersOfOtherTenants(t *testing.T) {
+	svc, e, db := newScheduleService(t)
+	if err := db.Exec("UPDATE hyadmin_users SET tenant_code = 'other' WHERE id = 2").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&UserRole{UserID: 2, RoleID: 7, TenantCode: "acme", ValidFrom: at(-time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	started, _, err := svc.applySchedules(testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(started) != 0 {
+		t.Errorf("started %v for a user that left the tenant", started)
+	}
+	if held, _ := e.HasGroupingPolicy(UserSubject(2), roleSubject(7), "acme"); held {
+		t.Error("g policy added for a user that left the tenant")
+	}
+	var rows int64
+	db.Model(&UserRole{}).Count(&rows)
+	if rows != 0 {
+		t.Error("the scheduled assignment was kept")
+	}
+}
+
+func TestApplySchedulesRunsOnce(t *testing.T) {
+	svc, _, db := newScheduleService(t)
+	if err := db.Create(&UserRole{UserID: 1, RoleID: 7, TenantCode: "acme", ValidFrom: at(-time.Minute), ValidUntil: at(time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if started, _, _ := svc.applySchedules(testNow); len(started) != 1 {
+		t.Fatalf("first run started %v", started)
+	}
+	if started, ended, _ := svc.applySchedules(testNow.Add(time.Minute)); len(started)+len(ended) != 0 {
+		t.Errorf("second run started %v, ended %v; want nothing", started, ended)
+	}
+	if _, ended, _ := svc.applySchedules(testNow.Add(time.Hour)); len(ended) != 1 {
+		t.Errorf("run at valid_until ended %v, want the assignment", ended)
+	}
+}
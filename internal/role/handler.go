@@ -1,23 +1,37 @@
 package role
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"github.com/robert7528/hycore/middleware"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/tenant"
 )
 
 type Handler struct {
-	svc *Service
+	svc   *Service
+	audit *auditlog.Service
 }
 
-func NewHandler(svc *Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *Service, audit *auditlog.Service) *Handler {
+	return &Handler{svc: svc, audit: audit}
 }
 
 // List GET /api/v1/admin/roles?tenant_code=...
+// tenant_code defaults to the caller's tenant.
 func (h *Handler) List(c *gin.Context) {
-	tc := c.Query("tenant_code")
+	tc, ok := tenant.CallerCode(c, c.Query("tenant_code"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	roles, err := h.svc.List(tc)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -33,6 +47,10 @@ func (h *Handler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !tenant.CanAccess(c, req.TenantCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	r, err := h.svc.Create(&req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -43,25 +61,27 @@ func (h *Handler) Create(c *gin.Context) {
 
 // Get GET /api/v1/admin/roles/:id
 func (h *Handler) Get(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
-	r, err := h.svc.GetByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	r, ok := h.load(c)
+	if !ok {
 		return
 	}
-	codes, _ := h.svc.GetPermissionCodes(uint(id))
-	c.JSON(http.StatusOK, gin.H{"role": r, "permission_codes": codes})
+	codes, deny, _ := h.svc.GetPermissionCodes(r.ID)
+	parents, _ := h.svc.GetParents(r.ID)
+	c.JSON(http.StatusOK, gin.H{"role": r, "permission_codes": codes, "deny_codes": deny, "parent_ids": parents})
 }
 
 // Update PUT /api/v1/admin/roles/:id
 func (h *Handler) Update(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
 	var req UpdateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.Update(uint(id), &req); err != nil {
+	if err := h.svc.Update(r.ID, &req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -70,8 +90,11 @@ func (h *Handler) Update(c *gin.Context) {
 
 // Delete DELETE /api/v1/admin/roles/:id
 func (h *Handler) Delete(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err := h.svc.Delete(uint(id)); err != nil {
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(r.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -80,26 +103,37 @@ func (h *Handler) Delete(c *gin.Context) {
 
 // GetPermissions GET /api/v1/admin/roles/:id/permissions
 func (h *Handler) GetPermissions(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
-	codes, err := h.svc.GetPermissionCodes(uint(id))
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
+	codes, deny, err := h.svc.GetPermissionCodes(r.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"permission_codes": codes})
+	c.JSON(http.StatusOK, gin.H{"permission_codes": codes, "deny_codes": deny})
 }
 
 // AssignPermissions PUT /api/v1/admin/roles/:id/permissions
+// Body: {"codes": [...], "deny": [...]}; entries are codes or patterns such
+// as "cert.*" or "*.view". A deny overrides any allow, including "*" and
+// allows inherited from other roles.
 func (h *Handler) AssignPermissions(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
-	var req struct {
-		Codes []string `json:"codes" binding:"required"`
+	r, ok := h.load(c)
+	if !ok {
+		return
 	}
+	var req SetPermissionsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.AssignPermissions(uint(id), req.Codes); err != nil {
+	if err := h.svc.AssignPermissions(r.ID, req.Codes, req.Deny); err != nil {
+		if errors.Is(err, ErrAllowDeny) || errors.Is(err, ErrWildcardTenant) || errors.Is(err, permission.ErrUnknownCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -107,29 +141,142 @@ func (h *Handler) AssignPermissions(c *gin.Context) {
 }
 
 // AssignUsers PUT /api/v1/admin/roles/:id/users
+// Adds the role to each user; the users must belong to the role's tenant.
+// Body: {"user_ids": [...]} for permanent assignments and/or
+// {"users": [{"user_id", "valid_from", "valid_until"}]} for time-bound ones.
 func (h *Handler) AssignUsers(c *gin.Context) {
-	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
 	var req AssignUsersRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	grants := req.Users
 	for _, uid := range req.UserIDs {
-		// get existing roles for user then add this role
-		roles, _ := h.svc.GetRolesForUser(uid)
-		rolesSet := make(map[uint]struct{})
-		for _, r := range roles {
-			rolesSet[r] = struct{}{}
-		}
-		rolesSet[uint(id)] = struct{}{}
-		newRoles := make([]uint, 0, len(rolesSet))
-		for r := range rolesSet {
-			newRoles = append(newRoles, r)
-		}
-		if err := h.svc.AssignRolesToUser(uid, newRoles); err != nil {
+		grants = append(grants, UserAssignment{UserID: uid})
+	}
+	if len(grants) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_ids or users is required"})
+		return
+	}
+	var actor uint
+	if claims := middleware.GetClaims(c); claims != nil {
+		actor = claims.UserID
+	}
+	assignments := make([]*UserRole, 0, len(grants))
+	for _, a := range grants {
+		ur, err := h.svc.GrantUserRole(r.ID, a, actor)
+		if err != nil {
+			if errors.Is(err, ErrUserTenant) || errors.Is(err, ErrRoleWindow) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		h.recordGrant(c, ur)
+		assignments = append(assignments, ur)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "users assigned to role", "assignments": assignments})
+}
+
+func (h *Handler) recordGrant(c *gin.Context, ur *UserRole) {
+	actor := middleware.GetClaims(c)
+	if actor == nil {
+		return
+	}
+	detail, _ := json.Marshal(map[string]interface{}{
+		"user_id":     ur.UserID,
+		"role_id":     ur.RoleID,
+		"valid_from":  ur.ValidFrom,
+		"valid_until": ur.ValidUntil,
+		"active":      ur.Active,
+	})
+	h.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: actor.TenantCode,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		Action:     "ROLE_ASSIGNMENT_GRANT",
+		Resource:   "roles",
+		ResourceID: strconv.FormatUint(uint64(ur.RoleID), 10),
+		Detail:     string(detail),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+}
+
+// EffectivePermissions GET /api/v1/admin/roles/:id/effective-permissions
+// Lists direct codes and codes inherited from parent roles.
+func (h *Handler) EffectivePermissions(c *gin.Context) {
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
+	ep, err := h.svc.EffectivePermissions(r.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ep)
+}
+
+// GetParents GET /api/v1/admin/roles/:id/parents
+func (h *Handler) GetParents(c *gin.Context) {
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
+	ids, err := h.svc.GetParents(r.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"parent_ids": ids})
+}
+
+// SetParents PUT /api/v1/admin/roles/:id/parents
+// Replaces the roles this role inherits permissions from.
+func (h *Handler) SetParents(c *gin.Context) {
+	r, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var req SetParentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.SetParents(r.ID, req.ParentIDs); err != nil {
+		switch {
+		case errors.Is(err, ErrRoleTenant), errors.Is(err, ErrRoleCycle), errors.Is(err, ErrRoleDepth):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "parent roles assigned"})
+}
+
+// load fetches the role named by :id, answering 404 if it does not exist and
+// 403 if the caller may not manage its tenant.
+func (h *Handler) load(c *gin.Context) (*Role, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	r, err := h.svc.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if !tenant.CanAccess(c, r.TenantCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "users assigned to role"})
+	return r, true
 }
@@ -19,11 +19,21 @@ type Role struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// UserRole is managed by Casbin (g policy), kept only for GORM auto-migrate reference.
-// Actual assignments live in casbin_rule table.
+// UserRole records the validity window of a direct user→role assignment.
+// The assignment itself is the Casbin g policy; the expiry job (see
+// ExpiryJob) adds it when ValidFrom arrives and removes it, together with
+// this row, once ValidUntil has passed. Permanent assignments have no window
+// and need no row.
 type UserRole struct {
-	UserID uint `gorm:"primaryKey"`
-	RoleID uint `gorm:"primaryKey"`
+	UserID     uint       `gorm:"primaryKey" json:"user_id"`
+	RoleID     uint       `gorm:"primaryKey" json:"role_id"`
+	TenantCode string     `gorm:"not null;default:''" json:"tenant_code"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `gorm:"index" json:"valid_until,omitempty"`
+	Active     bool       `gorm:"not null" json:"active"` // g policy in place
+	GrantedBy  uint       `json:"granted_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 type CreateRoleRequest struct {
@@ -41,6 +51,49 @@ type AssignPermissionsRequest struct {
 	PermissionIDs []uint `json:"permission_ids" binding:"required"`
 }
 
+// AssignUsersRequest adds users to a role: UserIDs permanently, Users with
+// an optional validity window each.
 type AssignUsersRequest struct {
-	UserIDs []uint `json:"user_ids" binding:"required"`
+	UserIDs []uint           `json:"user_ids"`
+	Users   []UserAssignment `json:"users" binding:"dive"`
+}
+
+// UserAssignment grants a role to a user from ValidFrom (default now) until
+// ValidUntil (default forever).
+type UserAssignment struct {
+	UserID     uint       `json:"user_id" binding:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+}
+
+type SetParentsRequest struct {
+	ParentIDs []uint `json:"parent_ids"`
+}
+
+// EffectivePermissions splits a role's codes into those assigned to it
+// directly and those inherited from ancestor roles. A deny anywhere in the
+// hierarchy overrides any allow of the same code.
+type EffectivePermissions struct {
+	RoleID     uint                  `json:"role_id"`
+	ParentIDs  []uint                `json:"parent_ids"`
+	Direct     []string              `json:"direct"`
+	DirectDeny []string              `json:"direct_deny"`
+	Inherited  []InheritedPermission `json:"inherited"` // entries the role does not hold directly
+	Effective  []string              `json:"effective"` // allowed and not denied
+	Denied     []string              `json:"denied"`
+}
+
+// InheritedPermission is a code, its effect and the ancestor role that sets it.
+type InheritedPermission struct {
+	Code     string `json:"code"`
+	Effect   string `json:"effect"` // allow|deny
+	RoleID   uint   `json:"role_id"`
+	RoleName string `json:"role_name"`
+}
+
+// SetPermissionsRequest replaces a role's policies. Codes are allowed,
+// Deny codes are denied even when another role or a wildcard allows them.
+type SetPermissionsRequest struct {
+	Codes []string `json:"codes" binding:"required"`
+	Deny  []string `json:"deny"`
 }
@@ -2,9 +2,13 @@ package role
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/hysp/hyadmin-api/internal/permission"
 )
 
 type Repository struct {
@@ -40,83 +44,124 @@ func (r *Repository) Delete(id uint) error {
 	return r.db.Delete(&Role{}, id).Error
 }
 
-// AssignPermissionsToRole replaces all p policies for this role with the given permission codes.
-func (r *Repository) AssignPermissionsToRole(roleID uint, codes []string) error {
-	sub := fmt.Sprintf("role:%d", roleID)
-	if _, err := r.enforcer.DeletePermissionsForUser(sub); err != nil {
+// Casbin policies are scoped by domain, the tenant code: p = sub, dom, obj,
+// act, eft and g = sub, role, dom. Every method takes the domain explicitly,
+// so a policy can never be read or written outside its tenant.
+
+// Policy effects; a matching deny overrides any allow.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// isDeny reports whether p = [sub, dom, obj, act, eft] is a deny; rules
+// written before the eft column existed are allows.
+func isDeny(p []string) bool { return len(p) > 4 && p[4] == EffectDeny }
+
+func roleSubject(roleID uint) string { return fmt.Sprintf("role:%d", roleID) }
+
+// AssignPermissionsToRole replaces all p policies for this role with the given allowed and denied codes.
+func (r *Repository) AssignPermissionsToRole(dom string, roleID uint, allow, deny []string) error {
+	sub := roleSubject(roleID)
+	if _, err := r.enforcer.RemoveFilteredPolicy(0, sub, dom); err != nil {
 		return err
 	}
-	for _, code := range codes {
-		if _, err := r.enforcer.AddPolicy(sub, code, "access"); err != nil {
+	for _, code := range allow {
+		if _, err := r.enforcer.AddPolicy(sub, dom, code, "access", EffectAllow); err != nil {
+			return err
+		}
+	}
+	for _, code := range deny {
+		if _, err := r.enforcer.AddPolicy(sub, dom, code, "access", EffectDeny); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// GetPermissionCodesForRole returns all permission codes assigned to a role.
-func (r *Repository) GetPermissionCodesForRole(roleID uint) ([]string, error) {
-	sub := fmt.Sprintf("role:%d", roleID)
-	policies, err := r.enforcer.GetPermissionsForUser(sub)
+// GetPermissionCodesForRole returns the codes a role allows and denies directly.
+func (r *Repository) GetPermissionCodesForRole(dom string, roleID uint) (allow, deny []string, err error) {
+	policies, err := r.enforcer.GetFilteredPolicy(0, roleSubject(roleID), dom)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	codes := make([]string, 0, len(policies))
+	allow, deny = []string{}, []string{}
 	for _, p := range policies {
-		if len(p) > 1 {
-			codes = append(codes, p[1]) // p = [sub, obj, act]
+		if len(p) < 3 {
+			continue
+		}
+		if isDeny(p) {
+			deny = append(deny, p[2])
+		} else {
+			allow = append(allow, p[2])
 		}
 	}
-	return codes, nil
+	return allow, deny, nil
 }
 
-// AssignRolesToUser replaces all g policies for this user with the given roles.
-func (r *Repository) AssignRolesToUser(userID uint, roleIDs []uint) error {
-	sub := fmt.Sprintf("user:%d", userID)
-	if _, err := r.enforcer.DeleteRolesForUser(sub); err != nil {
+// UserSubject and ServiceAccountSubject are the Casbin subjects of principals
+// that can hold roles.
+func UserSubject(userID uint) string       { return fmt.Sprintf("user:%d", userID) }
+func ServiceAccountSubject(id uint) string { return fmt.Sprintf("svc:%d", id) }
+
+// AssignRolesToSubject replaces all g policies for a subject in dom with the given roles.
+func (r *Repository) AssignRolesToSubject(sub, dom string, roleIDs []uint) error {
+	if _, err := r.enforcer.DeleteRolesForUser(sub, dom); err != nil {
 		return err
 	}
 	for _, rid := range roleIDs {
-		if _, err := r.enforcer.AddRoleForUser(sub, fmt.Sprintf("role:%d", rid)); err != nil {
+		if _, err := r.enforcer.AddRoleForUserInDomain(sub, roleSubject(rid), dom); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// GetPermissionCodesForUser collects all permission codes reachable by a user via their roles.
-func (r *Repository) GetPermissionCodesForUser(userID uint) ([]string, error) {
-	sub := fmt.Sprintf("user:%d", userID)
-	roles, err := r.enforcer.GetRolesForUser(sub)
+// GetPermissionCodesForSubject collects the codes a subject in dom is allowed
+// and denied via its roles, including those inherited from parent roles.
+// Allowed codes covered by a denied code or pattern are removed; an allow
+// pattern may still cover denied codes, so callers expanding patterns must
+// subtract deny again (see permission.Expand).
+func (r *Repository) GetPermissionCodesForSubject(sub, dom string) (allow, deny []string, err error) {
+	// Implicit roles are the transitive closure: direct roles and all their ancestors.
+	roles, err := r.enforcer.GetImplicitRolesForUser(sub, dom)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	codeSet := make(map[string]struct{})
+	allowSet := make(map[string]struct{})
+	denySet := make(map[string]struct{})
 	for _, roleSub := range roles {
-		policies, err := r.enforcer.GetPermissionsForUser(roleSub)
+		policies, err := r.enforcer.GetFilteredPolicy(0, roleSub, dom)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, p := range policies {
-			if len(p) > 1 {
-				codeSet[p[1]] = struct{}{}
+			if len(p) < 3 {
+				continue
+			}
+			if isDeny(p) {
+				denySet[p[2]] = struct{}{}
+			} else {
+				allowSet[p[2]] = struct{}{}
 			}
 		}
 	}
-	codes := make([]string, 0, len(codeSet))
-	for c := range codeSet {
-		codes = append(codes, c)
+	deny = make([]string, 0, len(denySet))
+	for c := range denySet {
+		deny = append(deny, c)
 	}
-	return codes, nil
+	allow = make([]string, 0, len(allowSet))
+	for c := range allowSet {
+		if !permission.MatchAny(deny, c) {
+			allow = append(allow, c)
+		}
+	}
+	return allow, deny, nil
 }
 
-// GetRolesForUser returns role IDs assigned to a user.
-func (r *Repository) GetRolesForUser(userID uint) ([]uint, error) {
-	sub := fmt.Sprintf("user:%d", userID)
-	roles, err := r.enforcer.GetRolesForUser(sub)
-	if err != nil {
-		return nil, err
-	}
+// GetRolesForSubject returns the IDs of the roles a subject holds in dom.
+func (r *Repository) GetRolesForSubject(sub, dom string) ([]uint, error) {
+	roles := r.enforcer.GetRolesForUserInDomain(sub, dom)
 	ids := make([]uint, 0, len(roles))
 	for _, rs := range roles {
 		var id uint
@@ -127,3 +172,190 @@ func (r *Repository) GetRolesForUser(userID uint) ([]uint, error) {
 	}
 	return ids, nil
 }
+
+// GetUsersForRole returns the IDs of users holding a role directly.
+func (r *Repository) GetUsersForRole(dom string, roleID uint) ([]uint, error) {
+	subs := r.enforcer.GetUsersForRoleInDomain(roleSubject(roleID), dom)
+	ids := make([]uint, 0, len(subs))
+	for _, sub := range subs {
+		var id uint
+		fmt.Sscanf(sub, "user:%d", &id)
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// AddUserToRole adds a single g policy; it is a no-op when the user already holds the role.
+func (r *Repository) AddUserToRole(dom string, userID, roleID uint) error {
+	_, err := r.enforcer.AddRoleForUserInDomain(UserSubject(userID), roleSubject(roleID), dom)
+	return err
+}
+
+// RemoveUserFromRole removes a single g policy.
+func (r *Repository) RemoveUserFromRole(dom string, userID, roleID uint) error {
+	_, err := r.enforcer.DeleteRoleForUserInDomain(UserSubject(userID), roleSubject(roleID), dom)
+	return err
+}
+
+// GetParents returns the IDs of the roles roleID inherits from directly
+// (g = role:child, role:parent, dom).
+func (r *Repository) GetParents(dom string, roleID uint) ([]uint, error) {
+	return r.GetRolesForSubject(roleSubject(roleID), dom)
+}
+
+// GetChildren returns the IDs of the roles that inherit from roleID directly.
+func (r *Repository) GetChildren(dom string, roleID uint) ([]uint, error) {
+	subs := r.enforcer.GetUsersForRoleInDomain(roleSubject(roleID), dom)
+	ids := make([]uint, 0, len(subs))
+	for _, sub := range subs {
+		var id uint
+		fmt.Sscanf(sub, "role:%d", &id)
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SetParents replaces the roles roleID inherits from.
+func (r *Repository) SetParents(dom string, roleID uint, parentIDs []uint) error {
+	return r.AssignRolesToSubject(roleSubject(roleID), dom, parentIDs)
+}
+
+// DeleteRolePolicies removes a role's permissions, its parents and every
+// assignment of it to users, service accounts and child roles.
+func (r *Repository) DeleteRolePolicies(dom string, roleID uint) error {
+	sub := roleSubject(roleID)
+	if _, err := r.enforcer.RemoveFilteredPolicy(0, sub, dom); err != nil {
+		return err
+	}
+	if _, err := r.enforcer.RemoveFilteredGroupingPolicy(0, sub, "", dom); err != nil {
+		return err
+	}
+	_, err := r.enforcer.RemoveFilteredGroupingPolicy(1, sub, dom)
+	return err
+}
+
+// SaveUserRole creates or replaces the window of a user's assignment.
+func (r *Repository) SaveUserRole(ur *UserRole) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tenant_code", "valid_from", "valid_until", "active", "granted_by", "updated_at"}),
+	}).Create(ur).Error
+}
+
+// DeleteUserRole removes the window of a single assignment.
+func (r *Repository) DeleteUserRole(userID, roleID uint) error {
+	return r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&UserRole{}).Error
+}
+
+// PruneUserRoles removes the windows of userID's assignments to roles other
+// than keep, scheduled ones included.
+func (r *Repository) PruneUserRoles(userID uint, keep []uint) error {
+	q := r.db.Where("user_id = ?", userID)
+	if len(keep) > 0 {
+		q = q.Where("role_id NOT IN ?", keep)
+	}
+	return q.Delete(&UserRole{}).Error
+}
+
+// ListUserRolesForRole returns the windows of every assignment of roleID.
+func (r *Repository) ListUserRolesForRole(roleID uint) ([]UserRole, error) {
+	var rows []UserRole
+	err := r.db.Where("role_id = ?", roleID).Order("user_id").Find(&rows).Error
+	return rows, err
+}
+
+// PendingRoleIDs returns the roles userID is scheduled to receive after now.
+func (r *Repository) PendingRoleIDs(userID uint, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&UserRole{}).Where("user_id = ? AND NOT active AND valid_from > ?", userID, now).
+		Pluck("role_id", &ids).Error
+	return ids, err
+}
+
+// scheduleLockKey is the Postgres advisory lock serialising runs of the
+// expiry job across replicas.
+const scheduleLockKey = 0x6879726f6c6573 // "hyroles"
+
+// WithScheduleLock runs fn while holding the expiry job's advisory lock. It
+// returns false without running fn when another replica holds the lock. The
+// lock is transaction-scoped, so it is released even if fn panics.
+func (r *Repository) WithScheduleLock(fn func() error) (bool, error) {
+	ran := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", scheduleLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		ran = true
+		return fn()
+	})
+	return ran, err
+}
+
+// hierarchyLockKey is the Postgres advisory lock serialising changes to role
+// inheritance, so a cycle check always sees the links written before it.
+const hierarchyLockKey = 0x68797061726e74 // "hyparnt"
+
+// WithHierarchyLock runs fn while holding the hierarchy lock, waiting for it
+// if another request holds it.
+func (r *Repository) WithHierarchyLock(fn func() error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", hierarchyLockKey).Error; err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// DeleteRoleAssignments removes every window of roleID's assignments.
+func (r *Repository) DeleteRoleAssignments(roleID uint) error {
+	return r.db.Where("role_id = ?", roleID).Delete(&UserRole{}).Error
+}
+
+// StartingUserRoles returns scheduled assignments whose window is open at now.
+func (r *Repository) StartingUserRoles(now time.Time) ([]UserRole, error) {
+	var rows []UserRole
+	err := r.db.Where("NOT active AND (valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now).
+		Order("user_id, role_id").Find(&rows).Error
+	return rows, err
+}
+
+// EndedUserRoles returns assignments whose window has closed by now.
+func (r *Repository) EndedUserRoles(now time.Time) ([]UserRole, error) {
+	var rows []UserRole
+	err := r.db.Where("valid_until <= ?", now).Order("user_id, role_id").Find(&rows).Error
+	return rows, err
+}
+
+// MarkUserRoleActive records that the g policy of an assignment is in place.
+func (r *Repository) MarkUserRoleActive(userID, roleID uint) error {
+	return r.db.Model(&UserRole{}).Where("user_id = ? AND role_id = ?", userID, roleID).
+		Updates(map[string]interface{}{"active": true, "updated_at": time.Now()}).Error
+}
+
+// UserTenants maps the given user IDs to their tenant codes; unknown IDs are left out.
+func (r *Repository) UserTenants(ids []uint) (map[uint]string, error) {
+	var rows []struct {
+		ID         uint
+		TenantCode string
+	}
+	tenants := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return tenants, nil
+	}
+	if err := r.db.Table("hyadmin_users").Select("id, tenant_code").
+		Where("id IN ? AND deleted_at IS NULL", ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		tenants[row.ID] = row.TenantCode
+	}
+	return tenants, nil
+}
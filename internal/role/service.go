@@ -1,11 +1,38 @@
 package role
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/tenant"
+)
+
+var (
+	ErrRoleTenant = errors.New("role: role belongs to another tenant")
+	ErrUserTenant = errors.New("role: user belongs to another tenant")
+	ErrRoleCycle  = errors.New("role: parent roles would form a cycle")
+	ErrRoleDepth  = errors.New("role: role hierarchy is too deep")
+	ErrAllowDeny  = errors.New("role: code is both allowed and denied")
+	ErrRoleWindow = errors.New("role: valid_until must be in the future and after valid_from")
+
+	ErrWildcardTenant = errors.New(`role: "*" may only be granted in the system tenant`)
+)
+
+// maxRoleDepth bounds the longest chain of roles linked by inheritance.
+// Casbin's default role manager follows at most 10 g links per check,
+// including the link from the user to its first role.
+const maxRoleDepth = 8
+
 type Service struct {
-	repo *Repository
+	repo  *Repository
+	perms *permission.Service
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, perms *permission.Service) *Service {
+	return &Service{repo: repo, perms: perms}
 }
 
 func (s *Service) Create(req *CreateRoleRequest) (*Role, error) {
@@ -39,26 +66,531 @@ func (s *Service) Update(id uint, req *UpdateRoleRequest) error {
 	return s.repo.Update(id, updates)
 }
 
+// Delete removes the role together with its policies, so neither its holders
+// nor its child roles keep its permissions.
 func (s *Service) Delete(id uint) error {
+	r, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRolePolicies(r.TenantCode, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRoleAssignments(id); err != nil {
+		return err
+	}
 	return s.repo.Delete(id)
 }
 
-func (s *Service) AssignPermissions(roleID uint, codes []string) error {
-	return s.repo.AssignPermissionsToRole(roleID, codes)
+// AssignPermissions replaces a role's allowed and denied codes or patterns
+// within the role's tenant.
+func (s *Service) AssignPermissions(roleID uint, allow, deny []string) error {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return err
+	}
+	allowed := make(map[string]bool, len(allow))
+	for _, code := range allow {
+		allowed[code] = true
+	}
+	// "*" makes a system-tenant principal a platform admin (see
+	// tenant.IsPlatformAdmin); elsewhere it would only be misleading.
+	if allowed["*"] && r.TenantCode != tenant.SystemCode {
+		return ErrWildcardTenant
+	}
+	for _, code := range deny {
+		if allowed[code] {
+			return fmt.Errorf("%w: %s", ErrAllowDeny, code)
+		}
+	}
+	// Codes may be patterns such as "cert.*" or "*.view"; each must cover
+	// part of the catalog so that a typo cannot silently grant nothing.
+	if err := s.perms.Validate(append(append([]string{}, allow...), deny...)); err != nil {
+		return err
+	}
+	return s.repo.AssignPermissionsToRole(r.TenantCode, roleID, allow, deny)
+}
+
+// GetPermissionCodes returns the codes a role allows and denies directly.
+func (s *Service) GetPermissionCodes(roleID uint) (allow, deny []string, err error) {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.GetPermissionCodesForRole(r.TenantCode, roleID)
+}
+
+// AssignRolesToUser replaces the roles of a user of tenantCode; every role
+// must belong to that tenant. Roles kept keep their windows, and roles whose
+// window has not opened yet stay scheduled: ApplySchedules grants them.
+func (s *Service) AssignRolesToUser(tenantCode string, userID uint, roleIDs []uint) error {
+	if err := s.checkRoles(tenantCode, roleIDs); err != nil {
+		return err
+	}
+	pending, err := s.repo.PendingRoleIDs(userID, time.Now())
+	if err != nil {
+		return err
+	}
+	scheduled := make(map[uint]bool, len(pending))
+	for _, id := range pending {
+		scheduled[id] = true
+	}
+	grant := make([]uint, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if !scheduled[id] {
+			grant = append(grant, id)
+		}
+	}
+	if err := s.repo.AssignRolesToSubject(UserSubject(userID), tenantCode, grant); err != nil {
+		return err
+	}
+	return s.repo.PruneUserRoles(userID, roleIDs)
+}
+
+// GetPermissionCodesForUser returns the codes the user is allowed, minus denied ones.
+func (s *Service) GetPermissionCodesForUser(tenantCode string, userID uint) ([]string, error) {
+	allow, _, err := s.repo.GetPermissionCodesForSubject(UserSubject(userID), tenantCode)
+	return allow, err
+}
+
+// GetDeniedCodesForUser returns the codes denied to the user; they override
+// wildcard allows such as "*".
+func (s *Service) GetDeniedCodesForUser(tenantCode string, userID uint) ([]string, error) {
+	_, deny, err := s.repo.GetPermissionCodesForSubject(UserSubject(userID), tenantCode)
+	return deny, err
+}
+
+// EffectiveCodesForUser returns the catalog codes the user's allows cover
+// and their denies do not, with patterns expanded.
+func (s *Service) EffectiveCodesForUser(tenantCode string, userID uint) ([]string, error) {
+	allow, deny, err := s.repo.GetPermissionCodesForSubject(UserSubject(userID), tenantCode)
+	if err != nil {
+		return nil, err
+	}
+	return s.perms.Expand(allow, deny)
+}
+
+func (s *Service) GetRolesForUser(tenantCode string, userID uint) ([]uint, error) {
+	return s.repo.GetRolesForSubject(UserSubject(userID), tenantCode)
+}
+
+func (s *Service) AssignRolesToServiceAccount(tenantCode string, id uint, roleIDs []uint) error {
+	if err := s.checkRoles(tenantCode, roleIDs); err != nil {
+		return err
+	}
+	return s.repo.AssignRolesToSubject(ServiceAccountSubject(id), tenantCode, roleIDs)
+}
+
+func (s *Service) GetPermissionCodesForServiceAccount(tenantCode string, id uint) ([]string, error) {
+	allow, _, err := s.repo.GetPermissionCodesForSubject(ServiceAccountSubject(id), tenantCode)
+	return allow, err
+}
+
+func (s *Service) GetDeniedCodesForServiceAccount(tenantCode string, id uint) ([]string, error) {
+	_, deny, err := s.repo.GetPermissionCodesForSubject(ServiceAccountSubject(id), tenantCode)
+	return deny, err
+}
+
+func (s *Service) GetRolesForServiceAccount(tenantCode string, id uint) ([]uint, error) {
+	return s.repo.GetRolesForSubject(ServiceAccountSubject(id), tenantCode)
+}
+
+func (s *Service) GetUsersForRole(roleID uint) ([]uint, error) {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetUsersForRole(r.TenantCode, roleID)
+}
+
+func (s *Service) AddUserToRole(userID, roleID uint) error {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUsers(r.TenantCode, []uint{userID}); err != nil {
+		return err
+	}
+	return s.repo.AddUserToRole(r.TenantCode, userID, roleID)
+}
+
+func (s *Service) RemoveUserFromRole(userID, roleID uint) error {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RemoveUserFromRole(r.TenantCode, userID, roleID); err != nil {
+		return err
+	}
+	return s.repo.DeleteUserRole(userID, roleID)
+}
+
+// GrantUserRole assigns the role to a user within the window of a. Without
+// a window the assignment is permanent; with a window that starts later the
+// role is only scheduled and ApplySchedules adds it when the window opens.
+func (s *Service) GrantUserRole(roleID uint, a UserAssignment, grantedBy uint) (*UserRole, error) {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkUsers(r.TenantCode, []uint{a.UserID}); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := checkWindow(a, now); err != nil {
+		return nil, err
+	}
+	ur := &UserRole{
+		UserID:     a.UserID,
+		RoleID:     roleID,
+		TenantCode: r.TenantCode,
+		ValidFrom:  a.ValidFrom,
+		ValidUntil: a.ValidUntil,
+		Active:     a.ValidFrom == nil || !a.ValidFrom.After(now),
+		GrantedBy:  grantedBy,
+	}
+	if a.ValidFrom == nil && a.ValidUntil == nil {
+		if err := s.repo.AddUserToRole(r.TenantCode, a.UserID, roleID); err != nil {
+			return nil, err
+		}
+		return ur, s.repo.DeleteUserRole(a.UserID, roleID)
+	}
+	// Save the window first, so a failure cannot leave an unbounded g policy.
+	if err := s.repo.SaveUserRole(ur); err != nil {
+		return nil, err
+	}
+	if ur.Active {
+		err = s.repo.AddUserToRole(r.TenantCode, a.UserID, roleID)
+	} else {
+		err = s.repo.RemoveUserFromRole(r.TenantCode, a.UserID, roleID)
+	}
+	return ur, err
+}
+
+// checkWindow rejects windows that have already closed at now or that close
+// before they open.
+func checkWindow(a UserAssignment, now time.Time) error {
+	if a.ValidUntil != nil && (!a.ValidUntil.After(now) || (a.ValidFrom != nil && !a.ValidUntil.After(*a.ValidFrom))) {
+		return ErrRoleWindow
+	}
+	return nil
+}
+
+// ApplySchedules adds the g policies of assignments whose window has opened
+// and removes those whose window has closed, returning both. Scheduled
+// assignments of users that left the role's tenant are dropped. Only one
+// replica applies schedules at a time; the others return nothing.
+func (s *Service) ApplySchedules(now time.Time) (started, ended []UserRole, err error) {
+	_, err = s.repo.WithScheduleLock(func() error {
+		started, ended, err = s.applySchedules(now)
+		return err
+	})
+	return started, ended, err
+}
+
+func (s *Service) applySchedules(now time.Time) (started, ended []UserRole, err error) {
+	due, err := s.repo.EndedUserRoles(now)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ur := range due {
+		if err := s.repo.RemoveUserFromRole(ur.TenantCode, ur.UserID, ur.RoleID); err != nil {
+			return started, ended, err
+		}
+		if err := s.repo.DeleteUserRole(ur.UserID, ur.RoleID); err != nil {
+			return started, ended, err
+		}
+		ended = append(ended, ur)
+	}
+	due, err = s.repo.StartingUserRoles(now)
+	if err != nil {
+		return started, ended, err
+	}
+	for _, ur := range due {
+		if err := s.checkUsers(ur.TenantCode, []uint{ur.UserID}); err != nil {
+			if errors.Is(err, ErrUserTenant) {
+				err = s.repo.DeleteUserRole(ur.UserID, ur.RoleID)
+			}
+			if err != nil {
+				return started, ended, err
+			}
+			continue
+		}
+		if err := s.repo.AddUserToRole(ur.TenantCode, ur.UserID, ur.RoleID); err != nil {
+			return started, ended, err
+		}
+		if err := s.repo.MarkUserRoleActive(ur.UserID, ur.RoleID); err != nil {
+			return started, ended, err
+		}
+		ur.Active = true
+		started = append(started, ur)
+	}
+	return started, ended, nil
+}
+
+// SetRoleUsers makes userIDs the exact set of users holding the role
+// directly and permanently: their windows are dropped, and so are the
+// assignments, scheduled ones included, of everyone else. Every user must
+// belong to the role's tenant.
+func (s *Service) SetRoleUsers(roleID uint, userIDs []uint) error {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUsers(r.TenantCode, userIDs); err != nil {
+		return err
+	}
+	holders, err := s.repo.GetUsersForRole(r.TenantCode, roleID)
+	if err != nil {
+		return err
+	}
+	windows, err := s.repo.ListUserRolesForRole(roleID)
+	if err != nil {
+		return err
+	}
+	want := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		want[id] = true
+	}
+	for _, ur := range windows {
+		if err := s.repo.DeleteUserRole(ur.UserID, roleID); err != nil {
+			return err
+		}
+		if !want[ur.UserID] {
+			holders = append(holders, ur.UserID)
+		}
+	}
+	for _, id := range holders {
+		if !want[id] {
+			if err := s.repo.RemoveUserFromRole(r.TenantCode, id, roleID); err != nil {
+				return err
+			}
+		}
+	}
+	for id := range want {
+		if err := s.repo.AddUserToRole(r.TenantCode, id, roleID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *Service) GetPermissionCodes(roleID uint) ([]string, error) {
-	return s.repo.GetPermissionCodesForRole(roleID)
+func (s *Service) GetParents(roleID uint) ([]uint, error) {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetParents(r.TenantCode, roleID)
 }
 
-func (s *Service) AssignRolesToUser(userID uint, roleIDs []uint) error {
-	return s.repo.AssignRolesToUser(userID, roleIDs)
+// SetParents makes the role inherit the permissions of parentIDs, which must
+// be roles of the same tenant. Links that would create a cycle or exceed
+// maxRoleDepth are rejected. The check and the write run under the hierarchy
+// lock, so concurrent calls cannot together form a cycle.
+func (s *Service) SetParents(roleID uint, parentIDs []uint) error {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRoles(r.TenantCode, parentIDs); err != nil {
+		return err
+	}
+	return s.repo.WithHierarchyLock(func() error {
+		if err := checkHierarchy(r.TenantCode, roleID, parentIDs, s.repo.GetParents, s.repo.GetChildren); err != nil {
+			return err
+		}
+		return s.repo.SetParents(r.TenantCode, roleID, parentIDs)
+	})
 }
 
-func (s *Service) GetPermissionCodesForUser(userID uint) ([]string, error) {
-	return s.repo.GetPermissionCodesForUser(userID)
+// links returns the roles linked to id in one direction: parents or children.
+type links func(dom string, id uint) ([]uint, error)
+
+// checkHierarchy reports whether roleID may inherit from parentIDs: no
+// parent may be roleID or inherit from it, and no chain through the new
+// links may hold more than maxRoleDepth roles.
+func checkHierarchy(dom string, roleID uint, parentIDs []uint, parents, children links) error {
+	below, err := depth(dom, roleID, children, map[uint]bool{})
+	if err != nil {
+		return err
+	}
+	for _, pid := range parentIDs {
+		if pid == roleID {
+			return ErrRoleCycle
+		}
+		up, err := ancestors(dom, pid, parents)
+		if err != nil {
+			return err
+		}
+		if up[roleID] {
+			return ErrRoleCycle
+		}
+		above, err := depth(dom, pid, parents, map[uint]bool{})
+		if err != nil {
+			return err
+		}
+		if below+above > maxRoleDepth {
+			return ErrRoleDepth
+		}
+	}
+	return nil
 }
 
-func (s *Service) GetRolesForUser(userID uint) ([]uint, error) {
-	return s.repo.GetRolesForUser(userID)
+// EffectivePermissions returns the role's direct codes and those it inherits
+// from its ancestors, applying deny-override.
+func (s *Service) EffectivePermissions(roleID uint) (*EffectivePermissions, error) {
+	r, err := s.repo.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	allow, deny, err := s.repo.GetPermissionCodesForRole(r.TenantCode, roleID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := s.repo.GetParents(r.TenantCode, roleID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := ancestors(r.TenantCode, roleID, s.repo.GetParents)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(ancestors))
+	for id := range ancestors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Codes keyed by effect, starting with the role's own policies.
+	held := map[string]map[string]bool{EffectAllow: {}, EffectDeny: {}}
+	for _, code := range allow {
+		held[EffectAllow][code] = true
+	}
+	for _, code := range deny {
+		held[EffectDeny][code] = true
+	}
+	ep := &EffectivePermissions{RoleID: roleID, ParentIDs: parents, Direct: allow, DirectDeny: deny, Inherited: []InheritedPermission{}}
+	effective := map[string]map[string]bool{EffectAllow: {}, EffectDeny: {}}
+	for effect, codes := range held {
+		for code := range codes {
+			effective[effect][code] = true
+		}
+	}
+	for _, id := range ids {
+		anc, err := s.repo.FindByID(id)
+		if err != nil {
+			continue // deleted role whose links were left behind
+		}
+		ancAllow, ancDeny, err := s.repo.GetPermissionCodesForRole(r.TenantCode, id)
+		if err != nil {
+			return nil, err
+		}
+		for effect, codes := range map[string][]string{EffectAllow: ancAllow, EffectDeny: ancDeny} {
+			for _, code := range codes {
+				effective[effect][code] = true
+				if !held[effect][code] {
+					ep.Inherited = append(ep.Inherited, InheritedPermission{Code: code, Effect: effect, RoleID: id, RoleName: anc.Name})
+				}
+			}
+		}
+	}
+	ep.Denied = make([]string, 0, len(effective[EffectDeny]))
+	for code := range effective[EffectDeny] {
+		ep.Denied = append(ep.Denied, code)
+	}
+	ep.Effective = []string{}
+	for code := range effective[EffectAllow] {
+		if !permission.MatchAny(ep.Denied, code) {
+			ep.Effective = append(ep.Effective, code)
+		}
+	}
+	sort.Slice(ep.Inherited, func(i, j int) bool {
+		a, b := ep.Inherited[i], ep.Inherited[j]
+		if a.RoleID != b.RoleID {
+			return a.RoleID < b.RoleID
+		}
+		if a.Effect != b.Effect {
+			return a.Effect < b.Effect
+		}
+		return a.Code < b.Code
+	})
+	sort.Strings(ep.Direct)
+	sort.Strings(ep.DirectDeny)
+	sort.Strings(ep.Effective)
+	sort.Strings(ep.Denied)
+	return ep, nil
+}
+
+// ancestors returns every role roleID inherits from, directly or not.
+func ancestors(dom string, roleID uint, parents links) (map[uint]bool, error) {
+	seen := map[uint]bool{}
+	queue := []uint{roleID}
+	for len(queue) > 0 {
+		ids, err := parents(dom, queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+		for _, p := range ids {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return seen, nil
+}
+
+// depth is the number of roles on the longest chain starting at roleID and
+// following next, roleID included. onPath holds the roles of the chain being
+// walked; meeting one again means the stored hierarchy already has a cycle.
+func depth(dom string, roleID uint, next links, onPath map[uint]bool) (int, error) {
+	if onPath[roleID] {
+		return 0, ErrRoleCycle
+	}
+	ids, err := next(dom, roleID)
+	if err != nil {
+		return 0, err
+	}
+	onPath[roleID] = true
+	defer delete(onPath, roleID)
+	longest := 0
+	for _, id := range ids {
+		d, err := depth(dom, id, next, onPath)
+		if err != nil {
+			return 0, err
+		}
+		if d > longest {
+			longest = d
+		}
+		if longest > maxRoleDepth {
+			break
+		}
+	}
+	return longest + 1, nil
+}
+
+// checkRoles rejects role IDs that are not roles of tenantCode.
+func (s *Service) checkRoles(tenantCode string, roleIDs []uint) error {
+	for _, id := range roleIDs {
+		r, err := s.repo.FindByID(id)
+		if err != nil || r.TenantCode != tenantCode {
+			return fmt.Errorf("%w: %d", ErrRoleTenant, id)
+		}
+	}
+	return nil
+}
+
+// checkUsers rejects user IDs that are not users of tenantCode.
+func (s *Service) checkUsers(tenantCode string, userIDs []uint) error {
+	tenants, err := s.repo.UserTenants(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range userIDs {
+		if tenants[id] != tenantCode {
+			return fmt.Errorf("%w: %d", ErrUserTenant, id)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,88 @@
+package role
+
+import (
+	"errors"
+	"testing"
+)
+
+// graph is a role hierarchy for tests, child -> parents.
+type graph map[uint][]uint
+
+func (g graph) parents(_ string, id uint) ([]uint, error) { return g[id], nil }
+
+func (g graph) children(_ string, id uint) ([]uint, error) {
+	var out []uint
+	for child, parents := range g {
+		for _, p := range parents {
+			if p == id {
+				out = append(out, child)
+			}
+		}
+	}
+	return out, nil
+}
+
+// chain links 1 <- 2 <- ... <- n, so n inherits from every lower role.
+func chain(n uint) graph {
+	g := graph{}
+	for id := uint(2); id <= n; id++ {
+		g[id] = []uint{id - 1}
+	}
+	return g
+}
+
+func TestCheckHierarchy(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       graph
+		role    uint
+		parents []uint
+		want    error
+	}{
+		{"no parents", graph{}, 1, nil, nil},
+		{"new link", graph{}, 2, []uint{1}, nil},
+		{"diamond", graph{2: {1}, 3: {1}}, 4, []uint{2, 3}, nil},
+		{"self", graph{}, 1, []uint{1}, ErrRoleCycle},
+		{"direct cycle", graph{2: {1}}, 1, []uint{2}, ErrRoleCycle},
+		{"indirect cycle", chain(4), 1, []uint{4}, ErrRoleCycle},
+		{"cycle through one of several parents", graph{2: {1}, 3: {2}}, 1, []uint{5, 3}, ErrRoleCycle},
+		{"max depth", chain(maxRoleDepth - 1), maxRoleDepth, []uint{maxRoleDepth - 1}, nil},
+		{"too deep above", chain(maxRoleDepth), maxRoleDepth + 1, []uint{maxRoleDepth}, ErrRoleDepth},
+		// 6..8 inherit from 5 and 9 from 4..1; linking 5 to 9 chains 9 roles.
+		{"too deep across", graph{2: {1}, 3: {2}, 4: {3}, 6: {5}, 7: {6}, 8: {7}, 9: {4}}, 5, []uint{9}, ErrRoleDepth},
+		{"existing cycle", graph{2: {3}, 3: {2}}, 1, []uint{2}, ErrRoleCycle},
+		{"existing cycle below", graph{1: {3, 2}, 2: {1}}, 3, []uint{4}, ErrRoleCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHierarchy("acme", tt.role, tt.parents, tt.g.parents, tt.g.children)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkHierarchy(%d, %v) = %v, want %v", tt.role, tt.parents, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		g    graph
+		role uint
+		want int
+		err  error
+	}{
+		{"alone", graph{}, 1, 1, nil},
+		{"chain", chain(5), 5, 5, nil},
+		{"longest branch", graph{5: {4, 1}, 4: {3}, 3: {2}}, 5, 4, nil},
+		{"cycle", graph{1: {2}, 2: {3}, 3: {1}}, 1, 0, ErrRoleCycle},
+		{"self loop", graph{1: {1}}, 1, 0, ErrRoleCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := depth("acme", tt.role, tt.g.parents, map[uint]bool{})
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("depth(%d) = %d, %v; want %d, %v", tt.role, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
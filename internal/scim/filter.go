@@ -0,0 +1,173 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// filter is a parsed SCIM filter restricted to what identity providers send
+// in practice: attribute comparisons (eq, ne, co, sw, ew, pr) joined by
+// "and"/"or", with "and" binding tighter. Grouping and value paths are not
+// supported. String comparisons are case-insensitive.
+type filter [][]comparison // OR of ANDs
+
+type comparison struct {
+	attr  string
+	op    string
+	value string
+}
+
+// parseFilter parses expr; an empty expression matches everything.
+func parseFilter(expr string) (filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	var f filter
+	var and []comparison
+	for i := 0; i < len(tokens); {
+		if len(tokens)-i < 2 {
+			return nil, fmt.Errorf("incomplete expression")
+		}
+		if strings.ContainsAny(tokens[i], "()[]") {
+			return nil, fmt.Errorf("grouping and value paths are not supported: %q", tokens[i])
+		}
+		c := comparison{attr: attrKey(tokens[i]), op: strings.ToLower(tokens[i+1])}
+		i += 2
+		switch c.op {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("missing value for %q", c.attr)
+			}
+			c.value = strings.Trim(tokens[i], `"`)
+			i++
+		default:
+			return nil, fmt.Errorf("unsupported operator %q", c.op)
+		}
+		and = append(and, c)
+		if i == len(tokens) {
+			break
+		}
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			f = append(f, and)
+			and = nil
+		default:
+			return nil, fmt.Errorf("unexpected %q", tokens[i])
+		}
+		i++
+		if i == len(tokens) {
+			return nil, fmt.Errorf("incomplete expression")
+		}
+	}
+	if len(and) > 0 {
+		f = append(f, and)
+	}
+	return f, nil
+}
+
+// attrKey lower-cases an attribute path and drops a schema URN prefix,
+// so "urn:ietf:params:scim:schemas:core:2.0:User:userName" becomes "username".
+func attrKey(path string) string {
+	if i := strings.LastIndex(path, ":"); i >= 0 && !strings.Contains(path[i:], `"`) {
+		path = path[i+1:]
+	}
+	return strings.ToLower(path)
+}
+
+// tokenize splits on spaces outside double-quoted strings.
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	quoted, escaped := false, false
+	for _, r := range expr {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case r == ' ' && !quoted:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+// match evaluates the filter against attrs, keyed by lower-case attribute path.
+func (f filter) match(attrs map[string][]string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, and := range f {
+		ok := true
+		for _, c := range and {
+			if !c.match(attrs[c.attr]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparison) match(values []string) bool {
+	if c.op == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if c.op == "ne" {
+		for _, v := range values {
+			if strings.EqualFold(v, c.value) {
+				return false
+			}
+		}
+		return true
+	}
+	want := strings.ToLower(c.value)
+	for _, v := range values {
+		v = strings.ToLower(v)
+		switch c.op {
+		case "eq":
+			if v == want {
+				return true
+			}
+		case "co":
+			if strings.Contains(v, want) {
+				return true
+			}
+		case "sw":
+			if strings.HasPrefix(v, want) {
+				return true
+			}
+		case "ew":
+			if strings.HasSuffix(v, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package scim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want filter
+	}{
+		{"", nil},
+		{`userName eq "alice"`, filter{{{"username", "eq", "alice"}}}},
+		{`userName EQ "alice"`, filter{{{"username", "eq", "alice"}}}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, filter{{{"username", "eq", "alice"}}}},
+		{`emails.value co "@example.com"`, filter{{{"emails.value", "co", "@example.com"}}}},
+		{`externalId pr`, filter{{{"externalid", "pr", ""}}}},
+		{`displayName eq "Alice Example"`, filter{{{"displayname", "eq", "Alice Example"}}}},
+		{`displayName eq "say \"hi\""`, filter{{{"displayname", "eq", `say "hi`}}}},
+		{`active eq true`, filter{{{"active", "eq", "true"}}}},
+		{`userName sw "a" and active eq true`, filter{{{"username", "sw", "a"}, {"active", "eq", "true"}}}},
+		{`userName eq "a" or userName eq "b"`, filter{{{"username", "eq", "a"}}, {{"username", "eq", "b"}}}},
+		{`userName eq "a" or userName ew "b" and externalId pr`, filter{
+			{{"username", "eq", "a"}},
+			{{"username", "ew", "b"}, {"externalid", "pr", ""}},
+		}},
+		{`  userName   eq   "alice"  `, filter{{{"username", "eq", "alice"}}}},
+	}
+	for _, tt := range tests {
+		got, err := parseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("parseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFilter(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		`userName`,
+		`userName eq`,
+		`userName gt "a"`,
+		`userName eq "alice`,
+		`userName eq "a" and`,
+		`userName eq "a" xor userName eq "b"`,
+		`userName eq "a" userName eq "b"`,
+		`(userName eq "a")`,
+		`emails[type eq "work"].value eq "a@b.test"`,
+		`userName eq "a" and (active eq true)`,
+	} {
+		if f, err := parseFilter(expr); err == nil {
+			t.Errorf("parseFilter(%q) = %v, want an error", expr, f)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	active := true
+	u := &User{
+		ID:          "7",
+		UserName:    "alice",
+		ExternalID:  "00u1",
+		DisplayName: "Alice Example",
+		Emails:      []MultiValue{{Value: "alice@example.com", Primary: true}, {Value: "a@corp.test"}},
+		Active:      &active,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{`userName eq "alice"`, true},
+		{`userName eq "ALICE"`, true},
+		{`userName eq "alic"`, false},
+		{`userName ne "bob"`, true},
+		{`userName ne "Alice"`, false},
+		{`displayName co "EXAMPLE"`, true},
+		{`displayName sw "ali"`, true},
+		{`displayName ew "ample"`, true},
+		{`displayName sw "example"`, false},
+		{`emails co "corp.test"`, true},
+		{`emails.value eq "alice@example.com"`, true},
+		{`emails ne "a@corp.test"`, false},
+		{`externalId pr`, true},
+		{`name.formatted pr`, false},
+		{`nickName pr`, false},
+		{`nickName eq "x"`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`userName eq "alice" and active eq false`, false},
+		{`userName eq "bob" or externalId eq "00u1"`, true},
+		{`userName eq "bob" or userName eq "carol"`, false},
+		{`meta.resourceType eq "User"`, true},
+	}
+	attrs := userAttrs(u)
+	for _, tt := range tests {
+		f, err := parseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("parseFilter(%q): %v", tt.expr, err)
+		}
+		if got := f.match(attrs); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
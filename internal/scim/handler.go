@@ -0,0 +1,322 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+)
+
+const (
+	contentType = "application/scim+json"
+	tokenKey    = "scim_token"
+)
+
+type Handler struct {
+	svc   *Service
+	audit *auditlog.Service
+}
+
+func NewHandler(svc *Service, audit *auditlog.Service) *Handler {
+	return &Handler{svc: svc, audit: audit}
+}
+
+// Middleware authenticates the tenant's SCIM bearer token for /scim/v2.
+func (h *Handler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		tok, err := h.svc.Authenticate(raw)
+		if !ok || err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			h.fail(c, &Error{Status: http.StatusUnauthorized, Detail: "invalid token"})
+			c.Abort()
+			return
+		}
+		c.Set(tokenKey, tok)
+		c.Next()
+	}
+}
+
+// ServiceProviderConfig GET /scim/v2/ServiceProviderConfig
+func (h *Handler) ServiceProviderConfig(c *gin.Context) {
+	h.write(c, http.StatusOK, gin.H{
+		"schemas":        []string{SchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": maxCount},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Per-tenant SCIM token issued by a hyadmin administrator",
+			"primary":     true,
+		}},
+	})
+}
+
+// ListUsers GET /scim/v2/Users
+func (h *Handler) ListUsers(c *gin.Context) {
+	res, err := h.svc.ListUsers(token(c), query(c))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.write(c, http.StatusOK, res)
+}
+
+// GetUser GET /scim/v2/Users/:id
+func (h *Handler) GetUser(c *gin.Context) {
+	u, err := h.svc.GetUser(token(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.write(c, http.StatusOK, u)
+}
+
+// CreateUser POST /scim/v2/Users
+func (h *Handler) CreateUser(c *gin.Context) {
+	var in User
+	if !h.bind(c, &in) {
+		return
+	}
+	u, err := h.svc.CreateUser(token(c), &in)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "CREATE", "Users", u.ID, u.UserName)
+	h.write(c, http.StatusCreated, u)
+}
+
+// ReplaceUser PUT /scim/v2/Users/:id
+func (h *Handler) ReplaceUser(c *gin.Context) {
+	var in User
+	if !h.bind(c, &in) {
+		return
+	}
+	u, err := h.svc.ReplaceUser(token(c), c.Param("id"), &in)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "UPDATE", "Users", u.ID, u.UserName)
+	h.write(c, http.StatusOK, u)
+}
+
+// PatchUser PATCH /scim/v2/Users/:id
+func (h *Handler) PatchUser(c *gin.Context) {
+	var req PatchRequest
+	if !h.bind(c, &req) {
+		return
+	}
+	u, err := h.svc.PatchUser(token(c), c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "UPDATE", "Users", u.ID, u.UserName)
+	h.write(c, http.StatusOK, u)
+}
+
+// DeleteUser DELETE /scim/v2/Users/:id
+func (h *Handler) DeleteUser(c *gin.Context) {
+	u, err := h.svc.DeleteUser(token(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "DELETE", "Users", u.ID, u.UserName)
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups GET /scim/v2/Groups
+func (h *Handler) ListGroups(c *gin.Context) {
+	res, err := h.svc.ListGroups(token(c), query(c))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.write(c, http.StatusOK, res)
+}
+
+// GetGroup GET /scim/v2/Groups/:id
+func (h *Handler) GetGroup(c *gin.Context) {
+	g, err := h.svc.GetGroup(token(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.write(c, http.StatusOK, g)
+}
+
+// CreateGroup POST /scim/v2/Groups
+func (h *Handler) CreateGroup(c *gin.Context) {
+	var in Group
+	if !h.bind(c, &in) {
+		return
+	}
+	g, err := h.svc.CreateGroup(token(c), &in)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "CREATE", "Groups", g.ID, g.DisplayName)
+	h.write(c, http.StatusCreated, g)
+}
+
+// ReplaceGroup PUT /scim/v2/Groups/:id
+func (h *Handler) ReplaceGroup(c *gin.Context) {
+	var in Group
+	if !h.bind(c, &in) {
+		return
+	}
+	g, err := h.svc.ReplaceGroup(token(c), c.Param("id"), &in)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "UPDATE", "Groups", g.ID, g.DisplayName)
+	h.write(c, http.StatusOK, g)
+}
+
+// PatchGroup PATCH /scim/v2/Groups/:id
+func (h *Handler) PatchGroup(c *gin.Context) {
+	var req PatchRequest
+	if !h.bind(c, &req) {
+		return
+	}
+	g, err := h.svc.PatchGroup(token(c), c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "UPDATE", "Groups", g.ID, g.DisplayName)
+	h.write(c, http.StatusOK, g)
+}
+
+// DeleteGroup DELETE /scim/v2/Groups/:id
+func (h *Handler) DeleteGroup(c *gin.Context) {
+	g, err := h.svc.DeleteGroup(token(c), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.record(c, "DELETE", "Groups", g.ID, g.DisplayName)
+	c.Status(http.StatusNoContent)
+}
+
+// ListTokens GET /api/v1/admin/tenants/:code/scim-tokens
+func (h *Handler) ListTokens(c *gin.Context) {
+	list, err := h.svc.ListTokens(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": list})
+}
+
+// CreateToken POST /api/v1/admin/tenants/:code/scim-tokens
+// The plaintext token is only returned by this call.
+func (h *Handler) CreateToken(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := h.svc.CreateToken(c.Param("code"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// RevokeToken DELETE /api/v1/admin/tenants/:code/scim-tokens/:id
+func (h *Handler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.RevokeToken(c.Param("code"), uint(id)); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func token(c *gin.Context) *Token {
+	tok, _ := c.MustGet(tokenKey).(*Token)
+	return tok
+}
+
+func query(c *gin.Context) *Query {
+	q := &Query{Filter: c.Query("filter"), StartIndex: 1, Count: defaultCount}
+	if v, err := strconv.Atoi(c.Query("startIndex")); err == nil {
+		q.StartIndex = v
+	}
+	if v, err := strconv.Atoi(c.Query("count")); err == nil {
+		q.Count = v
+	}
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if attrKey(strings.TrimSpace(attr)) == "members" {
+			q.ExcludeMember = true
+		}
+	}
+	return q
+}
+
+func (h *Handler) bind(c *gin.Context, v interface{}) bool {
+	if err := c.ShouldBindJSON(v); err != nil {
+		h.fail(c, errInvalid("invalidSyntax", "%v", err))
+		return false
+	}
+	return true
+}
+
+func (h *Handler) write(c *gin.Context, status int, v interface{}) {
+	c.Header("Content-Type", contentType)
+	c.JSON(status, v)
+}
+
+// fail renders err as a SCIM error response; unexpected errors become 500.
+func (h *Handler) fail(c *gin.Context, err error) {
+	var se *Error
+	if !errors.As(err, &se) {
+		se = &Error{Status: http.StatusInternalServerError, Detail: err.Error()}
+	}
+	body := gin.H{"schemas": []string{SchemaError}, "status": strconv.Itoa(se.Status), "detail": se.Detail}
+	if se.ScimType != "" {
+		body["scimType"] = se.ScimType
+	}
+	h.write(c, se.Status, body)
+}
+
+// record audits a provisioning write under the token, as "scim:<token name>".
+func (h *Handler) record(c *gin.Context, action, resource, id, name string) {
+	tok := token(c)
+	detail, _ := json.Marshal(map[string]interface{}{"name": name, "token_id": tok.ID})
+	h.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: tok.TenantCode,
+		Username:   "scim:" + tok.Name,
+		Action:     action,
+		Resource:   "scim/" + resource,
+		ResourceID: id,
+		Detail:     string(detail),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+}
@@ -0,0 +1,118 @@
+package scim
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func (Token) TableName() string { return "hyadmin_scim_tokens" }
+
+// Token authenticates an identity provider pushing users and groups into one
+// tenant. The secret is shown once at creation; only its SHA-256 hash is stored.
+// Users created through the token get Provider, so set it to the OIDC/LDAP
+// provider name when the IdP's externalId is the same subject users log in with.
+type Token struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	TenantCode string     `gorm:"index;not null" json:"tenant_code"`
+	Name       string     `gorm:"not null" json:"name"`
+	Provider   string     `gorm:"not null;default:'scim'" json:"provider"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type TokenRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Provider string `json:"provider"` // default "scim"
+}
+
+// CreatedToken is returned once, with the plaintext token.
+type CreatedToken struct {
+	*Token
+	Secret string `json:"token"`
+}
+
+// SCIM message and resource schemas (RFC 7643/7644).
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is a SCIM multi-valued attribute entry (emails, members).
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// User maps to an AdminUser: userName to Username, externalId to ProviderID,
+// displayName (or name) to DisplayName, the primary email to Email and
+// active to Enabled.
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Password    string       `json:"password,omitempty"` // write-only
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// Group maps to a role of the tenant; members are the users holding it.
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []PatchOp `json:"Operations" binding:"required"`
+}
+
+type PatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Query holds the list parameters of GET /Users and /Groups.
+type Query struct {
+	Filter        string
+	StartIndex    int
+	Count         int
+	ExcludeMember bool // excludedAttributes=members
+}
@@ -0,0 +1,43 @@
+package scim
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(t *Token) error {
+	return r.db.Create(t).Error
+}
+
+func (r *Repository) FindByHash(hash string) (*Token, error) {
+	var t Token
+	err := r.db.Where("token_hash = ?", hash).First(&t).Error
+	return &t, err
+}
+
+func (r *Repository) ListByTenant(tenantCode string) ([]Token, error) {
+	var list []Token
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// Revoke revokes one of the tenant's tokens; it reports false if there was nothing to revoke.
+func (r *Repository) Revoke(tenantCode string, id uint, now time.Time) (bool, error) {
+	res := r.db.Model(&Token{}).
+		Where("id = ? AND tenant_code = ? AND revoked_at IS NULL", id, tenantCode).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *Repository) Touch(id uint, now time.Time) error {
+	return r.db.Model(&Token{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
@@ -0,0 +1,113 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPatchUser(t *testing.T) {
+	yes, no := true, false
+	base := func() *User {
+		return &User{
+			UserName:    "alice",
+			ExternalID:  "00u1",
+			DisplayName: "Alice Example",
+			Name:        &Name{Formatted: "Alice Example", GivenName: "Alice", FamilyName: "Example"},
+			Emails:      []MultiValue{{Value: "alice@example.com", Primary: true}},
+			Active:      &yes,
+		}
+	}
+	tests := []struct {
+		name string
+		op   PatchOp
+		want func(u *User)
+	}{
+		{"replace active", PatchOp{Op: "replace", Path: "active", Value: json.RawMessage(`false`)},
+			func(u *User) { u.Active = &no }},
+		{"active as string", PatchOp{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)},
+			func(u *User) { u.Active = &no }},
+		{"no path", PatchOp{Op: "replace", Value: json.RawMessage(`{"active":false,"userName":"alice2"}`)},
+			func(u *User) { u.Active, u.UserName = &no, "alice2" }},
+		{"urn path", PatchOp{Op: "replace", Path: "urn:ietf:params:scim:schemas:core:2.0:User:displayName", Value: json.RawMessage(`"Ally"`)},
+			func(u *User) { u.DisplayName = "Ally" }},
+		{"given name", PatchOp{Op: "replace", Path: "name.givenName", Value: json.RawMessage(`"Alicia"`)},
+			func(u *User) { u.Name.GivenName, u.Name.Formatted, u.DisplayName = "Alicia", "", "" }},
+		{"name object", PatchOp{Op: "add", Path: "name", Value: json.RawMessage(`{"formatted":"A. Example"}`)},
+			func(u *User) { u.Name = &Name{Formatted: "A. Example"} }},
+		{"email string", PatchOp{Op: "replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"alice@corp.test"`)},
+			func(u *User) { u.Emails = []MultiValue{{Value: "alice@corp.test", Primary: true}} }},
+		{"email list", PatchOp{Op: "add", Path: "emails", Value: json.RawMessage(`[{"value":"a@b.test","type":"work"}]`)},
+			func(u *User) { u.Emails = []MultiValue{{Value: "a@b.test", Type: "work"}} }},
+		{"unknown attribute ignored", PatchOp{Op: "replace", Path: "title", Value: json.RawMessage(`"CTO"`)},
+			func(u *User) {}},
+		{"extension ignored", PatchOp{Op: "add", Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", Value: json.RawMessage(`"IT"`)},
+			func(u *User) {}},
+		{"remove display name", PatchOp{Op: "remove", Path: "displayName"},
+			func(u *User) { u.DisplayName, u.Name = "", nil }},
+		{"remove external id", PatchOp{Op: "remove", Path: "externalId"},
+			func(u *User) { u.ExternalID = "" }},
+		{"remove emails", PatchOp{Op: "remove", Path: `emails[value eq "alice@example.com"]`},
+			func(u *User) { u.Emails = nil }},
+		{"remove unknown ignored", PatchOp{Op: "remove", Path: "title"},
+			func(u *User) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := base(), base()
+			tt.want(want)
+			if err := patchUser(got, tt.op); err != nil {
+				t.Fatalf("patchUser: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("patchUser = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatchUserErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       PatchOp
+		scimType string
+	}{
+		{"unknown op", PatchOp{Op: "move", Path: "active", Value: json.RawMessage(`true`)}, "invalidSyntax"},
+		{"no path, not an object", PatchOp{Op: "replace", Value: json.RawMessage(`"x"`)}, "invalidSyntax"},
+		{"remove without path", PatchOp{Op: "remove"}, "noTarget"},
+		{"active not a boolean", PatchOp{Op: "replace", Path: "active", Value: json.RawMessage(`"maybe"`)}, "invalidValue"},
+		{"userName not a string", PatchOp{Op: "replace", Path: "userName", Value: json.RawMessage(`42`)}, "invalidValue"},
+		{"emails not a list or string", PatchOp{Op: "replace", Path: "emails", Value: json.RawMessage(`{}`)}, "invalidValue"},
+		{"name not an object", PatchOp{Op: "replace", Path: "name", Value: json.RawMessage(`"Alice"`)}, "invalidValue"},
+		{"bad value in object", PatchOp{Op: "add", Value: json.RawMessage(`{"active":"maybe"}`)}, "invalidValue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := patchUser(&User{}, tt.op)
+			var se *Error
+			if !errors.As(err, &se) || se.ScimType != tt.scimType {
+				t.Fatalf("patchUser = %v, want a %s error", err, tt.scimType)
+			}
+		})
+	}
+}
+
+func TestMemberFilter(t *testing.T) {
+	tests := map[string]string{
+		`members[value eq "12"]`: "12",
+		`members[value eq 12]`:   "12",
+		`Members[Value EQ "7"]`:  "7",
+	}
+	for path, want := range tests {
+		m := memberFilter.FindStringSubmatch(path)
+		if m == nil || m[1] != want {
+			t.Errorf("memberFilter(%q) = %v, want member %s", path, m, want)
+		}
+	}
+	for _, path := range []string{"members", `members[display eq "x"]`, `members[value eq "1"].display`} {
+		if memberFilter.MatchString(path) {
+			t.Errorf("memberFilter matched %q", path)
+		}
+	}
+}
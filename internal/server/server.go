@@ -4,22 +4,38 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/apitoken"
 	"github.com/hysp/hyadmin-api/internal/auditlog"
+	localauth "github.com/hysp/hyadmin-api/internal/auth"
 	"github.com/hysp/hyadmin-api/internal/feature"
 	"github.com/hysp/hyadmin-api/internal/health"
+	"github.com/hysp/hyadmin-api/internal/invitation"
+	"github.com/hysp/hyadmin-api/internal/ldapauth"
+	"github.com/hysp/hyadmin-api/internal/lockout"
+	"github.com/hysp/hyadmin-api/internal/mfa"
+	"github.com/hysp/hyadmin-api/internal/oidc"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/passwordreset"
 	"github.com/hysp/hyadmin-api/internal/pbmodule"
 	"github.com/hysp/hyadmin-api/internal/permission"
+	"github.com/hysp/hyadmin-api/internal/privacy"
+	"github.com/hysp/hyadmin-api/internal/provisioning"
 	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/scim"
+	"github.com/hysp/hyadmin-api/internal/serviceaccount"
+	"github.com/hysp/hyadmin-api/internal/session"
+	"github.com/hysp/hyadmin-api/internal/setting"
 	"github.com/hysp/hyadmin-api/internal/tenant"
-	coreauth "github.com/robert7528/hycore/auth"
-	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"github.com/hysp/hyadmin-api/internal/userbulk"
 	"github.com/robert7528/hycore/config"
 	"github.com/robert7528/hycore/database"
 	"github.com/robert7528/hycore/middleware"
+	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -31,10 +47,32 @@ type Server struct {
 	log    *zap.Logger
 }
 
-func New(cfg *config.Config, log *zap.Logger) *Server {
+func New(cfg *config.Config, log *zap.Logger) (*Server, error) {
 	gin.SetMode(cfg.Server.Mode)
 	engine := gin.New()
-	return &Server{engine: engine, cfg: cfg, log: log}
+	// ClientIP keys the per-IP login throttle, so X-Forwarded-For is only
+	// honoured from the proxies listed here; with none it is ignored.
+	proxies := trustedProxies()
+	if err := engine.SetTrustedProxies(proxies); err != nil {
+		return nil, fmt.Errorf("server.trusted_proxies: %w", err)
+	}
+	log.Info("trusted proxies", zap.Strings("proxies", proxies))
+	return &Server{engine: engine, cfg: cfg, log: log}, nil
+}
+
+// trustedProxies reads server.trusted_proxies (env SERVER_TRUSTED_PROXIES),
+// a list or comma-separated string of IPs and CIDRs. It lives outside
+// config.ServerConfig, so it is read from the loaded Viper config directly.
+func trustedProxies() []string {
+	var out []string
+	for _, v := range viper.GetStringSlice("server.trusted_proxies") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
 }
 
 // RouteParams groups all handler dependencies for fx injection.
@@ -50,9 +88,28 @@ type RouteParams struct {
 	Role       *role.Handler
 	RoleSvc    *role.Service
 	Permission *permission.Handler
-	Auth       *coreauth.Handler
-	AuthSvc    *coreauth.Service
+	PermSvc    *permission.Service
+	Auth       *localauth.Handler
+	AuthSvc    *localauth.Service
+	Session    *session.Handler
+	MFA        *mfa.Handler
+	Lockout    *lockout.Handler
+	Password   *password.Handler
+	Reset      *passwordreset.Handler
+	Invitation *invitation.Handler
+	UserBulk   *userbulk.Handler
+	Privacy    *privacy.Handler
+	OIDC       *oidc.Handler
+	RoleMap    *provisioning.Handler
+	LDAP       *ldapauth.Handler
+	SCIM       *scim.Handler
+	Token      *apitoken.Handler
+	TokenSvc   *apitoken.Service
+	SvcAccount *serviceaccount.Handler
+	Setting    *setting.Handler
+	SessionSvc *session.Service
 	AuditLog   *auditlog.Handler
+	AuditSvc   *auditlog.Service
 	Enforcer   *casbin.Enforcer
 	DBManager  *database.DBManager
 }
@@ -66,21 +123,48 @@ func RegisterRoutes(p RouteParams) {
 	// ── Public routes (no JWT) ──────────────────────────────────────────
 	api.GET("/health", p.Health.Check)
 	api.POST("/auth/login", p.Auth.Login)
+	api.POST("/auth/login/mfa", p.Auth.LoginMFA)
+	api.POST("/auth/refresh", p.Auth.Refresh)
 	api.POST("/auth/logout", p.Auth.Logout)
+	api.POST("/auth/token", p.Auth.Token)
+	api.GET("/auth/password-policy", p.Password.Policy)
+	api.POST("/auth/password/forgot", p.Reset.Forgot)
+	api.POST("/auth/password/reset", p.Reset.Reset)
+	api.GET("/auth/invitations/:token", p.Invitation.Preview)
+	api.POST("/auth/invitations/accept", p.Invitation.Accept)
+	api.GET("/auth/oidc/providers", p.OIDC.Providers)
+	api.GET("/auth/oidc/:provider/start", p.OIDC.Start)
+	api.GET("/auth/oidc/:provider/callback", p.OIDC.Callback)
 
 	// ── JWT-protected routes ────────────────────────────────────────────
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware(p.AuthSvc))
-	protected.Use(middleware.PermissionLoaderMiddleware(p.RoleSvc))
+	protected.Use(localauth.AuthMiddleware(p.AuthSvc, p.SessionSvc, p.TokenSvc))
+	protected.Use(localauth.PermissionLoaderMiddleware(p.RoleSvc))
 	{
 		// User-facing: modules & features (filtered by permissions)
 		protected.GET("/modules", p.Module.ListForUser)
 		protected.GET("/features", p.Feature.ListByModule)
 
-		// Current user's permission codes
+		// Current user's permission codes; patterns are expanded to the
+		// catalog codes they grant, minus denied ones
 		protected.GET("/permissions/me", func(c *gin.Context) {
-			codes := middleware.GetPermissionCodes(c)
-			c.JSON(http.StatusOK, gin.H{"permissions": codes})
+			patterns := middleware.GetPermissionCodes(c)
+			denied := localauth.GetDeniedCodes(c)
+			codes, err := p.PermSvc.Expand(patterns, denied)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			resp := gin.H{"permissions": codes, "patterns": patterns, "denied": denied}
+			if claims := localauth.GetClaims(c); claims != nil && claims.Impersonator != nil {
+				resp["impersonation"] = gin.H{
+					"user_id":      claims.UserID,
+					"username":     claims.Username,
+					"impersonator": claims.Impersonator,
+					"expires_at":   claims.ExpiresAt,
+				}
+			}
+			c.JSON(http.StatusOK, resp)
 		})
 
 		// Profile routes (use JWT claims for user identity)
@@ -117,11 +201,24 @@ func RegisterRoutes(p RouteParams) {
 					return
 				}
 				if err := p.AdminUser.ChangeSelfPassword(c, claims.UserID, &req); err != nil {
+					if password.WritePolicyError(c, err) {
+						return
+					}
 					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 					return
 				}
 				c.JSON(http.StatusOK, gin.H{"message": "password updated"})
 			})
+			profile.GET("/sessions", p.Session.ListMine)
+			profile.DELETE("/sessions/:id", p.Session.RevokeMine)
+			profile.GET("/mfa", p.MFA.Status)
+			profile.POST("/mfa/totp/setup", p.MFA.SetupTOTP)
+			profile.POST("/mfa/totp/confirm", p.MFA.ConfirmTOTP)
+			profile.POST("/mfa/totp/disable", p.MFA.DisableTOTP)
+			profile.POST("/mfa/recovery-codes", p.MFA.RegenerateRecoveryCodes)
+			profile.GET("/tokens", p.Token.ListMine)
+			profile.POST("/tokens", p.Token.CreateMine)
+			profile.DELETE("/tokens/:id", p.Token.RevokeMine)
 		}
 
 		// Tenant CRUD (admin)
@@ -137,8 +234,8 @@ func RegisterRoutes(p RouteParams) {
 
 		// Admin routes
 		admin := protected.Group("/admin")
-		admin.Use(middleware.PermissionMiddleware(p.Enforcer))
-		admin.Use(coreauditlog.AuditMiddleware(p.DB))
+		admin.Use(localauth.PermissionMiddleware(p.Enforcer))
+		admin.Use(localauth.AuditMiddleware(p.AuditSvc))
 		{
 			// Modules
 			mods := admin.Group("/modules")
@@ -174,18 +271,99 @@ func RegisterRoutes(p RouteParams) {
 
 			// Users
 			users := admin.Group("/users")
+			userTenant := p.AdminUser.RequireTenantAccess()
 			{
 				users.GET("", p.AdminUser.List)
 				users.POST("", p.AdminUser.Create)
+				users.POST("/import", localauth.RequirePermission("users.list.import"), p.UserBulk.Import)
+				users.GET("/export", localauth.RequirePermission("users.list.export"), p.UserBulk.Export)
 				users.GET("/:id", p.AdminUser.Get)
 				users.PUT("/:id", p.AdminUser.Update)
-				users.PUT("/:id/password", p.AdminUser.ChangePassword)
+				users.PUT("/:id/password", userTenant, p.AdminUser.ResetPassword)
 				users.DELETE("/:id", p.AdminUser.Delete)
+				users.GET("/:id/sessions", userTenant, p.Session.ListForUser)
+				users.DELETE("/:id/sessions", userTenant, p.Session.RevokeForUser)
+				users.GET("/:id/lockout", userTenant, p.Lockout.Status)
+				users.POST("/:id/unlock", userTenant, p.Lockout.Unlock)
+				users.GET("/:id/tokens", userTenant, p.Token.ListForUser)
+				users.DELETE("/:id/tokens/:tokenId", userTenant, p.Token.RevokeForUser)
+				users.POST("/:id/invitation", userTenant, p.Invitation.InviteUser)
+				users.POST("/:id/impersonate", localauth.RequirePermission("users.list.impersonate"), p.Auth.Impersonate)
+				users.GET("/:id/personal-data", localauth.RequirePermission("users.list.personal_data"), userTenant, p.Privacy.Export)
+				users.POST("/:id/erase", localauth.RequirePermission("users.list.erase"), userTenant, p.Privacy.Erase)
+			}
+
+			// Service accounts
+			svcAccounts := admin.Group("/service-accounts")
+			{
+				svcAccounts.GET("", p.SvcAccount.List)
+				svcAccounts.POST("", p.SvcAccount.Create)
+				svcAccounts.GET("/:id", p.SvcAccount.Get)
+				svcAccounts.PUT("/:id", p.SvcAccount.Update)
+				svcAccounts.DELETE("/:id", p.SvcAccount.Delete)
+				svcAccounts.POST("/:id/secret", p.SvcAccount.RotateSecret)
+				svcAccounts.GET("/:id/roles", p.SvcAccount.GetRoles)
+				svcAccounts.PUT("/:id/roles", p.SvcAccount.AssignRoles)
 			}
 
 			// Audit logs
 			admin.GET("/audit-logs", p.AuditLog.List)
 
+			// Per-tenant setting overrides
+			tenantSettings := admin.Group("/tenants/:code/settings")
+			tenantSettings.Use(tenant.RequireAccess("code"))
+			{
+				tenantSettings.GET("", p.Setting.ListTenant)
+				tenantSettings.PUT("/:key", p.Setting.PutTenant)
+				tenantSettings.DELETE("/:key", p.Setting.DeleteTenant)
+			}
+
+			// Per-tenant OIDC identity providers
+			oidcProviders := admin.Group("/tenants/:code/oidc-providers")
+			oidcProviders.Use(tenant.RequireAccess("code"))
+			{
+				oidcProviders.GET("", p.OIDC.List)
+				oidcProviders.POST("", p.OIDC.Create)
+				oidcProviders.PUT("/:id", p.OIDC.Update)
+				oidcProviders.DELETE("/:id", p.OIDC.Delete)
+			}
+
+			// Per-tenant LDAP / AD server
+			ldapServer := admin.Group("/tenants/:code/ldap")
+			ldapServer.Use(tenant.RequireAccess("code"))
+			{
+				ldapServer.GET("", p.LDAP.Get)
+				ldapServer.PUT("", p.LDAP.Put)
+				ldapServer.DELETE("", p.LDAP.Delete)
+			}
+
+			// Per-tenant IdP group/claim to role mapping rules
+			roleMappings := admin.Group("/tenants/:code/role-mappings")
+			roleMappings.Use(tenant.RequireAccess("code"))
+			{
+				roleMappings.GET("", p.RoleMap.List)
+				roleMappings.POST("", p.RoleMap.Create)
+				roleMappings.DELETE("/:id", p.RoleMap.Delete)
+			}
+
+			// Per-tenant SCIM provisioning tokens
+			scimTokens := admin.Group("/tenants/:code/scim-tokens")
+			scimTokens.Use(tenant.RequireAccess("code"))
+			{
+				scimTokens.GET("", p.SCIM.ListTokens)
+				scimTokens.POST("", p.SCIM.CreateToken)
+				scimTokens.DELETE("/:id", p.SCIM.RevokeToken)
+			}
+
+			// Invitations
+			invitations := admin.Group("/invitations")
+			{
+				invitations.GET("", p.Invitation.List)
+				invitations.POST("", p.Invitation.Create)
+				invitations.POST("/:id/resend", p.Invitation.Resend)
+				invitations.DELETE("/:id", p.Invitation.Revoke)
+			}
+
 			// Roles
 			roles := admin.Group("/roles")
 			{
@@ -197,6 +375,9 @@ func RegisterRoutes(p RouteParams) {
 				roles.GET("/:id/permissions", p.Role.GetPermissions)
 				roles.PUT("/:id/permissions", p.Role.AssignPermissions)
 				roles.PUT("/:id/users", p.Role.AssignUsers)
+				roles.GET("/:id/parents", p.Role.GetParents)
+				roles.PUT("/:id/parents", p.Role.SetParents)
+				roles.GET("/:id/effective-permissions", p.Role.EffectivePermissions)
 			}
 		}
 
@@ -208,6 +389,25 @@ func RegisterRoutes(p RouteParams) {
 			_ = data
 		}
 	}
+
+	// ── SCIM 2.0 provisioning (per-tenant SCIM bearer token) ────────────
+	scimAPI := r.Group("/scim/v2")
+	scimAPI.Use(p.SCIM.Middleware())
+	{
+		scimAPI.GET("/ServiceProviderConfig", p.SCIM.ServiceProviderConfig)
+		scimAPI.GET("/Users", p.SCIM.ListUsers)
+		scimAPI.POST("/Users", p.SCIM.CreateUser)
+		scimAPI.GET("/Users/:id", p.SCIM.GetUser)
+		scimAPI.PUT("/Users/:id", p.SCIM.ReplaceUser)
+		scimAPI.PATCH("/Users/:id", p.SCIM.PatchUser)
+		scimAPI.DELETE("/Users/:id", p.SCIM.DeleteUser)
+		scimAPI.GET("/Groups", p.SCIM.ListGroups)
+		scimAPI.POST("/Groups", p.SCIM.CreateGroup)
+		scimAPI.GET("/Groups/:id", p.SCIM.GetGroup)
+		scimAPI.PUT("/Groups/:id", p.SCIM.ReplaceGroup)
+		scimAPI.PATCH("/Groups/:id", p.SCIM.PatchGroup)
+		scimAPI.DELETE("/Groups/:id", p.SCIM.DeleteGroup)
+	}
 }
 
 func Start(lc fx.Lifecycle, s *Server) {
@@ -0,0 +1,172 @@
+package serviceaccount
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/hysp/hyadmin-api/internal/tenant"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// List GET /api/v1/admin/service-accounts?tenant_code=...
+// tenant_code defaults to the caller's tenant.
+func (h *Handler) List(c *gin.Context) {
+	tc, ok := tenant.CallerCode(c, c.Query("tenant_code"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	list, err := h.svc.List(tc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"service_accounts": list})
+}
+
+// Create POST /api/v1/admin/service-accounts
+// The client secret is only returned in this response. tenant_code defaults
+// to the caller's tenant; roles must belong to the account's tenant.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tc, ok := tenant.CallerCode(c, req.TenantCode)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	req.TenantCode = tc
+	creds, err := h.svc.Create(&req)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, creds)
+}
+
+// Get GET /api/v1/admin/service-accounts/:id
+func (h *Handler) Get(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// Update PUT /api/v1/admin/service-accounts/:id
+func (h *Handler) Update(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Update(a.ID, &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "updated"})
+}
+
+// Delete DELETE /api/v1/admin/service-accounts/:id
+func (h *Handler) Delete(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RotateSecret POST /api/v1/admin/service-accounts/:id/secret
+func (h *Handler) RotateSecret(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	creds, err := h.svc.RotateSecret(a.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, creds)
+}
+
+// GetRoles GET /api/v1/admin/service-accounts/:id/roles
+func (h *Handler) GetRoles(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	ids, err := h.svc.GetRoles(a.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role_ids": ids})
+}
+
+// AssignRoles PUT /api/v1/admin/service-accounts/:id/roles
+func (h *Handler) AssignRoles(c *gin.Context) {
+	a, ok := h.load(c)
+	if !ok {
+		return
+	}
+	var req AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.AssignRoles(a.ID, req.RoleIDs); err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "roles assigned"})
+}
+
+// load fetches the account named by :id, answering 404 if it does not exist
+// and 403 if the caller may not act on its tenant.
+func (h *Handler) load(c *gin.Context) (*ServiceAccount, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	a, err := h.svc.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if !tenant.CanAccess(c, a.TenantCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return a, true
+}
@@ -0,0 +1,50 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (ServiceAccount) TableName() string { return "hyadmin_service_accounts" }
+
+// ServiceAccount is a non-human principal of a tenant. It cannot log in
+// interactively; it exchanges ClientID and a secret for a short-lived access
+// token at /auth/token and holds roles through Casbin subject "svc:<id>".
+type ServiceAccount struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TenantCode  string         `gorm:"index;not null" json:"tenant_code"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `json:"description"`
+	ClientID    string         `gorm:"size:64;uniqueIndex;not null" json:"client_id"`
+	SecretHash  string         `gorm:"size:64;not null" json:"-"` // SHA-256 of the high-entropy secret
+	Enabled     bool           `gorm:"default:true" json:"enabled"`
+	LastUsedAt  *time.Time     `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type CreateRequest struct {
+	TenantCode  string `json:"tenant_code"` // default: the caller's tenant
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	RoleIDs     []uint `json:"role_ids"`
+}
+
+type UpdateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+type AssignRolesRequest struct {
+	RoleIDs []uint `json:"role_ids" binding:"required"`
+}
+
+// Credentials is returned when an account is created or its secret rotated;
+// the secret is not retrievable afterwards.
+type Credentials struct {
+	*ServiceAccount
+	ClientSecret string `json:"client_secret"`
+}
@@ -0,0 +1,49 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(a *ServiceAccount) error {
+	return r.db.Create(a).Error
+}
+
+func (r *Repository) FindByID(id uint) (*ServiceAccount, error) {
+	var a ServiceAccount
+	err := r.db.First(&a, id).Error
+	return &a, err
+}
+
+func (r *Repository) FindByClientID(clientID string) (*ServiceAccount, error) {
+	var a ServiceAccount
+	err := r.db.Where("client_id = ?", clientID).First(&a).Error
+	return &a, err
+}
+
+func (r *Repository) List(tenantCode string) ([]ServiceAccount, error) {
+	var list []ServiceAccount
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *Repository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&ServiceAccount{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *Repository) Touch(id uint, now time.Time) error {
+	return r.db.Model(&ServiceAccount{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
+
+func (r *Repository) Delete(id uint) error {
+	return r.db.Delete(&ServiceAccount{}, id).Error
+}
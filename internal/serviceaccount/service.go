@@ -0,0 +1,175 @@
+// Package serviceaccount manages non-human principals that authenticate
+// with OAuth2 client credentials.
+package serviceaccount
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hysp/hyadmin-api/internal/role"
+)
+
+var (
+	ErrInvalidClient = errors.New("serviceaccount: invalid client credentials")
+	ErrRoleNotFound  = errors.New("serviceaccount: role not found in tenant")
+)
+
+type Service struct {
+	repo  *Repository
+	roles *role.Service
+}
+
+func NewService(repo *Repository, roles *role.Service) *Service {
+	return &Service{repo: repo, roles: roles}
+}
+
+func (s *Service) Create(req *CreateRequest) (*Credentials, error) {
+	clientID, err := randomString("sa_", 12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString("", 32)
+	if err != nil {
+		return nil, err
+	}
+	a := &ServiceAccount{
+		TenantCode:  req.TenantCode,
+		Name:        req.Name,
+		Description: req.Description,
+		ClientID:    clientID,
+		SecretHash:  hashSecret(secret),
+		Enabled:     true,
+	}
+	if err := s.checkRoles(a.TenantCode, req.RoleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(a); err != nil {
+		return nil, err
+	}
+	if len(req.RoleIDs) > 0 {
+		if err := s.roles.AssignRolesToServiceAccount(a.TenantCode, a.ID, req.RoleIDs); err != nil {
+			return nil, err
+		}
+	}
+	return &Credentials{ServiceAccount: a, ClientSecret: secret}, nil
+}
+
+func (s *Service) GetByID(id uint) (*ServiceAccount, error) {
+	return s.repo.FindByID(id)
+}
+
+func (s *Service) List(tenantCode string) ([]ServiceAccount, error) {
+	return s.repo.List(tenantCode)
+}
+
+func (s *Service) Update(id uint, req *UpdateRequest) error {
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	return s.repo.Update(id, updates)
+}
+
+// RotateSecret replaces the client secret. Tokens already issued stay valid
+// until they expire; disable the account to cut them off immediately.
+func (s *Service) RotateSecret(id uint) (*Credentials, error) {
+	a, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString("", 32)
+	if err != nil {
+		return nil, err
+	}
+	a.SecretHash = hashSecret(secret)
+	if err := s.repo.Update(id, map[string]interface{}{"secret_hash": a.SecretHash}); err != nil {
+		return nil, err
+	}
+	return &Credentials{ServiceAccount: a, ClientSecret: secret}, nil
+}
+
+func (s *Service) Delete(id uint) error {
+	a, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	return s.roles.AssignRolesToServiceAccount(a.TenantCode, id, nil)
+}
+
+func (s *Service) AssignRoles(id uint, roleIDs []uint) error {
+	a, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRoles(a.TenantCode, roleIDs); err != nil {
+		return err
+	}
+	return s.roles.AssignRolesToServiceAccount(a.TenantCode, id, roleIDs)
+}
+
+func (s *Service) GetRoles(id uint) ([]uint, error) {
+	a, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.roles.GetRolesForServiceAccount(a.TenantCode, id)
+}
+
+// Authenticate checks client credentials. Every failure, including a
+// disabled account, yields ErrInvalidClient.
+func (s *Service) Authenticate(clientID, secret string) (*ServiceAccount, error) {
+	a, err := s.repo.FindByClientID(clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(a.SecretHash)) != 1 || !a.Enabled {
+		return nil, ErrInvalidClient
+	}
+	_ = s.repo.Touch(a.ID, time.Now())
+	return a, nil
+}
+
+// IsActive reports whether tokens of the account may still be used.
+func (s *Service) IsActive(id uint) bool {
+	a, err := s.repo.FindByID(id)
+	return err == nil && a.Enabled
+}
+
+// checkRoles rejects roles of another tenant.
+func (s *Service) checkRoles(tenantCode string, roleIDs []uint) error {
+	for _, id := range roleIDs {
+		r, err := s.roles.GetByID(id)
+		if err != nil || r.TenantCode != tenantCode {
+			return fmt.Errorf("%w: %d", ErrRoleNotFound, id)
+		}
+	}
+	return nil
+}
+
+func randomString(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("serviceaccount: random: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package session
+
+import "time"
+
+// Session scopes. Session.Scope holds a space-separated list of pending
+// obligations; an empty scope is a normal, unrestricted session.
+const (
+	// ScopeMFAEnroll restricts a session to TOTP enrollment when the tenant
+	// requires MFA and the user has not enrolled yet.
+	ScopeMFAEnroll = "mfa_enroll"
+	// ScopePasswordChange restricts a session to changing the password when
+	// it must be changed (set by an admin) or has expired.
+	ScopePasswordChange = "password_change"
+)
+
+func (Session) TableName() string      { return "hyadmin_sessions" }
+func (RotatedToken) TableName() string { return "hyadmin_session_rotated_tokens" }
+
+// Session is a server-side login session.
+// Its ID is the jti of every access token issued for it; the refresh token is
+// rotated on each use and only its SHA-256 hash is persisted.
+type Session struct {
+	ID               string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID           uint       `gorm:"index;not null" json:"user_id"`
+	TenantCode       string     `gorm:"index;not null" json:"tenant_code"`
+	Username         string     `gorm:"not null" json:"username"`
+	Provider         string     `gorm:"not null" json:"provider"`
+	Scope            string     `json:"scope,omitempty"` // space-separated; non-empty = restricted session
+	RefreshTokenHash string     `gorm:"not null" json:"-"`
+	IP               string     `json:"ip"`
+	UserAgent        string     `json:"user_agent"`
+	ExpiresAt        time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason     string     `json:"revoke_reason,omitempty"`   // logout|refresh_reuse|...
+	ImpersonatorID   *uint      `json:"impersonator_id,omitempty"` // set when an admin acts as this user
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	Current bool `gorm:"-" json:"current"` // set when listing: the caller's own session
+}
+
+// RotatedToken is the hash of a refresh token that was superseded by rotation.
+// Presenting one again means the token leaked, so the session is revoked; any
+// other wrong secret is simply rejected.
+type RotatedToken struct {
+	TokenHash string    `gorm:"primaryKey;size:64"`
+	SessionID string    `gorm:"index;not null;size:64"`
+	RotatedAt time.Time `gorm:"not null"`
+}
+
+// Active reports whether the session can still be used at time now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ClientInfo describes the client that opened or refreshed a session.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
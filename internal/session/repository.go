@@ -0,0 +1,82 @@
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(s *Session) error {
+	return r.db.Create(s).Error
+}
+
+func (r *Repository) FindByID(id string) (*Session, error) {
+	var s Session
+	err := r.db.Where("id = ?", id).First(&s).Error
+	return &s, err
+}
+
+func (r *Repository) ListActiveByUser(userID uint, now time.Time) ([]Session, error) {
+	var sessions []Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Rotate swaps the refresh token hash only if it still equals oldHash,
+// so two concurrent refreshes with the same token cannot both succeed.
+// oldHash is kept as a RotatedToken to recognise later reuse.
+func (r *Repository) Rotate(id, oldHash, newHash string, info ClientInfo, now time.Time) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Session{}).
+			Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+			Updates(map[string]interface{}{
+				"refresh_token_hash": newHash,
+				"ip":                 info.IP,
+				"user_agent":         info.UserAgent,
+				"last_used_at":       now,
+			})
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+		rotated = true
+		return tx.Create(&RotatedToken{TokenHash: oldHash, SessionID: id, RotatedAt: now}).Error
+	})
+	return rotated && err == nil, err
+}
+
+// WasRotated reports whether hash is a superseded refresh token of the session.
+func (r *Repository) WasRotated(id, hash string) (bool, error) {
+	var n int64
+	err := r.db.Model(&RotatedToken{}).Where("session_id = ? AND token_hash = ?", id, hash).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *Repository) Revoke(id, reason string, now time.Time) error {
+	return r.db.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason}).Error
+}
+
+func (r *Repository) RevokeAllForUser(userID uint, reason string, now time.Time) error {
+	return r.db.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason}).Error
+}
+
+func (r *Repository) UpdateScope(id, scope string) error {
+	return r.db.Model(&Session{}).Where("id = ?", id).Update("scope", scope).Error
+}
+
+func (r *Repository) SetImpersonator(id string, impersonatorID uint) error {
+	return r.db.Model(&Session{}).Where("id = ?", id).Update("impersonator_id", impersonatorID).Error
+}
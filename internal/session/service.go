@@ -0,0 +1,206 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	coreauth "github.com/robert7528/hycore/auth"
+)
+
+var (
+	ErrNotFound            = errors.New("session: not found")
+	ErrInvalidRefreshToken = errors.New("session: invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("session: refresh token reused")
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Create opens a session for the authenticated claims and returns it together
+// with the plaintext refresh token. The token is never stored.
+// A non-empty scope restricts every access token issued for the session.
+func (s *Service) Create(claims *coreauth.Claims, scope string, info ClientInfo, ttl time.Duration) (*Session, string, error) {
+	id, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	sess := &Session{
+		ID:               id,
+		UserID:           claims.UserID,
+		TenantCode:       claims.TenantCode,
+		Username:         claims.Username,
+		Provider:         claims.Provider,
+		Scope:            scope,
+		RefreshTokenHash: hashSecret(secret),
+		IP:               info.IP,
+		UserAgent:        info.UserAgent,
+		ExpiresAt:        now.Add(ttl),
+		LastUsedAt:       now,
+	}
+	if err := s.repo.Create(sess); err != nil {
+		return nil, "", fmt.Errorf("session: create: %w", err)
+	}
+	return sess, id + "." + secret, nil
+}
+
+// CreateImpersonation opens a session for claims.UserID on behalf of an
+// administrator. Its refresh token is never handed out, so the session ends
+// when ttl runs out or it is revoked.
+func (s *Service) CreateImpersonation(claims *coreauth.Claims, impersonatorID uint, info ClientInfo, ttl time.Duration) (*Session, error) {
+	sess, _, err := s.Create(claims, "", info, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetImpersonator(sess.ID, impersonatorID); err != nil {
+		return nil, err
+	}
+	sess.ImpersonatorID = &impersonatorID
+	return sess, nil
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that has
+// already been rotated out revokes the whole session (reuse detection).
+func (s *Service) Rotate(refreshToken string, info ClientInfo) (*Session, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	sess, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	if !sess.Active(now) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if hash := hashSecret(secret); hash != sess.RefreshTokenHash {
+		// Only a token this session already rotated away proves a leak;
+		// a secret that never belonged to it must not end the session.
+		reused, err := s.repo.WasRotated(sess.ID, hash)
+		if err != nil {
+			return nil, "", err
+		}
+		if !reused {
+			return nil, "", ErrInvalidRefreshToken
+		}
+		_ = s.repo.Revoke(sess.ID, "refresh_reuse", now)
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	newSecret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	rotated, err := s.repo.Rotate(sess.ID, sess.RefreshTokenHash, hashSecret(newSecret), info, now)
+	if err != nil {
+		return nil, "", fmt.Errorf("session: rotate: %w", err)
+	}
+	if !rotated {
+		// Lost a race against another refresh with the same token.
+		_ = s.repo.Revoke(sess.ID, "refresh_reuse", now)
+		return nil, "", ErrRefreshTokenReused
+	}
+	return sess, sess.ID + "." + newSecret, nil
+}
+
+// RevokeByRefreshToken ends the session a refresh token belongs to.
+func (s *Service) RevokeByRefreshToken(refreshToken, reason string) error {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return ErrInvalidRefreshToken
+	}
+	sess, err := s.repo.FindByID(id)
+	if err != nil || hashSecret(secret) != sess.RefreshTokenHash {
+		return ErrInvalidRefreshToken
+	}
+	return s.repo.Revoke(sess.ID, reason, time.Now())
+}
+
+// Revoke ends a session by ID.
+func (s *Service) Revoke(id, reason string) error {
+	return s.repo.Revoke(id, reason, time.Now())
+}
+
+// RemoveScope drops one obligation from a restricted session once the user
+// has fulfilled it; with none left the session has full access again.
+// Tokens already issued keep their scope until refreshed.
+func (s *Service) RemoveScope(id, scope string) error {
+	sess, err := s.repo.FindByID(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	remaining := make([]string, 0)
+	for _, sc := range strings.Fields(sess.Scope) {
+		if sc != scope {
+			remaining = append(remaining, sc)
+		}
+	}
+	return s.repo.UpdateScope(id, strings.Join(remaining, " "))
+}
+
+// RevokeForUser ends a session only if it belongs to userID.
+func (s *Service) RevokeForUser(userID uint, id, reason string) error {
+	sess, err := s.repo.FindByID(id)
+	if err != nil || sess.UserID != userID {
+		return ErrNotFound
+	}
+	return s.repo.Revoke(sess.ID, reason, time.Now())
+}
+
+// RevokeAllForUser ends every open session of a user.
+func (s *Service) RevokeAllForUser(userID uint, reason string) error {
+	return s.repo.RevokeAllForUser(userID, reason, time.Now())
+}
+
+// ListActive returns a user's open sessions, flagging currentID as the caller's.
+func (s *Service) ListActive(userID uint, currentID string) ([]Session, error) {
+	sessions, err := s.repo.ListActiveByUser(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// IsActive reports whether the session exists, belongs to userID and is neither revoked nor expired.
+func (s *Service) IsActive(id string, userID uint) bool {
+	if id == "" {
+		return false
+	}
+	sess, err := s.repo.FindByID(id)
+	if err != nil {
+		return false
+	}
+	return sess.UserID == userID && sess.Active(time.Now())
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("session: random: %w", err)
+	}
+	return encode(b), nil
+}
@@ -0,0 +1,154 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	coreauth "github.com/robert7528/hycore/auth"
+	"gorm.io/gorm"
+)
+
+func newTestService(t *testing.T) (*Service, *Repository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection would get its own :memory: database
+	if err := db.AutoMigrate(&Session{}, &RotatedToken{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(db)
+	return NewService(repo), repo
+}
+
+func openSession(t *testing.T, svc *Service) (*Session, string) {
+	t.Helper()
+	claims := coreauth.NewClaims(7, "acme", "alice", "local", 1)
+	sess, refresh, err := svc.Create(claims, "", ClientInfo{IP: "10.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess, refresh
+}
+
+func TestRotateIssuesNewToken(t *testing.T) {
+	svc, repo := newTestService(t)
+	sess, refresh := openSession(t, svc)
+
+	got, next, err := svc.Rotate(refresh, ClientInfo{IP: "10.0.0.2", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if got.ID != sess.ID || next == refresh {
+		t.Fatalf("Rotate returned session %s and token %q, want the same session and a new token", got.ID, next)
+	}
+	if _, _, err := svc.Rotate(next, ClientInfo{}); err != nil {
+		t.Fatalf("rotating the new token: %v", err)
+	}
+	stored, err := repo.FindByID(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, oldSecret, _ := strings.Cut(refresh, ".")
+	if stored.RefreshTokenHash == hashSecret(oldSecret) {
+		t.Error("the old refresh token hash is still stored")
+	}
+}
+
+func TestRotateDetectsReuse(t *testing.T) {
+	svc, repo := newTestService(t)
+	sess, refresh := openSession(t, svc)
+
+	_, next, err := svc.Rotate(refresh, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The old token is presented again, e.g. by someone who stole it.
+	if _, _, err := svc.Rotate(refresh, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	stored, err := repo.FindByID(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == nil || stored.RevokeReason != "refresh_reuse" {
+		t.Fatalf("session revoked_at=%v reason=%q, want revoked for refresh_reuse", stored.RevokedAt, stored.RevokeReason)
+	}
+	// The legitimate holder's current token dies with the session.
+	if _, _, err := svc.Rotate(next, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("current token after reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRotateDetectsReuseOfOlderTokens(t *testing.T) {
+	svc, repo := newTestService(t)
+	sess, first := openSession(t, svc)
+
+	token := first
+	for range 3 {
+		_, next, err := svc.Rotate(token, ClientInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token = next
+	}
+	if _, _, err := svc.Rotate(first, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("token from three rotations ago: err = %v, want ErrRefreshTokenReused", err)
+	}
+	stored, err := repo.FindByID(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == nil {
+		t.Fatal("session not revoked")
+	}
+}
+
+func TestRotateWrongSecretKeepsSession(t *testing.T) {
+	svc, repo := newTestService(t)
+	sess, refresh := openSession(t, svc)
+
+	if _, _, err := svc.Rotate(sess.ID+".not-a-token-of-this-session", ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("wrong secret: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	stored, err := repo.FindByID(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt != nil {
+		t.Fatalf("session revoked for %q by a wrong secret", stored.RevokeReason)
+	}
+	if _, _, err := svc.Rotate(refresh, ClientInfo{}); err != nil {
+		t.Fatalf("current token after a wrong secret: %v", err)
+	}
+}
+
+func TestRotateRejectsInvalidTokens(t *testing.T) {
+	svc, _ := newTestService(t)
+	sess, _ := openSession(t, svc)
+	revoked, revokedToken := openSession(t, svc)
+	if err := svc.Revoke(revoked.ID, "logout"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"empty":           "",
+		"no secret":       sess.ID + ".",
+		"no separator":    sess.ID,
+		"unknown session": "0123456789abcdef.secret",
+		"revoked session": revokedToken,
+	}
+	for name, token := range tests {
+		if _, _, err := svc.Rotate(token, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidRefreshToken", name, err)
+		}
+	}
+}
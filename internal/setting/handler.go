@@ -0,0 +1,53 @@
+package setting
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robert7528/hycore/middleware"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+// ListTenant GET /api/v1/admin/tenants/:code/settings
+func (h *Handler) ListTenant(c *gin.Context) {
+	settings, err := h.svc.ListTenant(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// PutTenant PUT /api/v1/admin/tenants/:code/settings/:key
+func (h *Handler) PutTenant(c *gin.Context) {
+	var req PutTenantSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updatedBy := ""
+	if claims := middleware.GetClaims(c); claims != nil {
+		updatedBy = claims.Username
+	}
+	if err := h.svc.PutTenant(c.Param("code"), c.Param("key"), req.Value, updatedBy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "updated"})
+}
+
+// DeleteTenant DELETE /api/v1/admin/tenants/:code/settings/:key
+func (h *Handler) DeleteTenant(c *gin.Context) {
+	if err := h.svc.DeleteTenant(c.Param("code"), c.Param("key")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
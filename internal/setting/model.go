@@ -0,0 +1,49 @@
+package setting
+
+import "time"
+
+func (Setting) TableName() string       { return "hyadmin_settings" }
+func (TenantSetting) TableName() string { return "hyadmin_tenant_settings" }
+
+// Setting is a hot-updatable application setting (seeded by `hyadmin seed`).
+// Value is always stored as text; Type tells the UI how to render it.
+type Setting struct {
+	Key         string    `gorm:"primaryKey" json:"key"`
+	Value       string    `gorm:"not null;default:''" json:"value"`
+	Type        string    `gorm:"not null;default:'string'" json:"type"` // string|integer|boolean
+	GroupName   string    `gorm:"not null;default:'general'" json:"group_name"`
+	Description string    `json:"description"`
+	IsPublic    bool      `gorm:"default:false" json:"is_public"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   string    `json:"updated_by"`
+}
+
+// TenantSetting overrides a global Setting for a single tenant.
+type TenantSetting struct {
+	TenantCode string    `gorm:"primaryKey;size:100" json:"tenant_code"`
+	Key        string    `gorm:"primaryKey;size:255" json:"key"`
+	Value      string    `gorm:"not null;default:''" json:"value"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UpdatedBy  string    `json:"updated_by"`
+}
+
+// TenantOverridable lists the setting keys a tenant may override.
+var TenantOverridable = map[string]bool{
+	"auth.mfa.required":                         true,
+	"auth.password.min_length":                  true,
+	"auth.password.require_uppercase":           true,
+	"auth.password.require_lowercase":           true,
+	"auth.password.require_digit":               true,
+	"auth.password.require_symbol":              true,
+	"auth.password.deny_common":                 true,
+	"auth.password.history_count":               true,
+	"auth.password.max_age_days":                true,
+	"auth.provisioning.jit_enabled":             true,
+	"auth.pat.max_days":                         true,
+	"auth.service_account.token_expire_minutes": true,
+	"auth.invitation.expire_hours":              true,
+}
+
+type PutTenantSettingRequest struct {
+	Value string `json:"value"`
+}
@@ -0,0 +1,43 @@
+package setting
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindByKey(key string) (*Setting, error) {
+	var s Setting
+	err := r.db.Where("key = ?", key).First(&s).Error
+	return &s, err
+}
+
+func (r *Repository) FindTenantByKey(tenantCode, key string) (*TenantSetting, error) {
+	var s TenantSetting
+	err := r.db.Where("tenant_code = ? AND key = ?", tenantCode, key).First(&s).Error
+	return &s, err
+}
+
+func (r *Repository) ListTenant(tenantCode string) ([]TenantSetting, error) {
+	var settings []TenantSetting
+	err := r.db.Where("tenant_code = ?", tenantCode).Order("key").Find(&settings).Error
+	return settings, err
+}
+
+func (r *Repository) UpsertTenant(s *TenantSetting) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_code"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at", "updated_by"}),
+	}).Create(s).Error
+}
+
+func (r *Repository) DeleteTenant(tenantCode, key string) error {
+	return r.db.Where("tenant_code = ? AND key = ?", tenantCode, key).Delete(&TenantSetting{}).Error
+}
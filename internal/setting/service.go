@@ -0,0 +1,94 @@
+package setting
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Service reads typed values from hyadmin_settings.
+// Missing keys or unparsable values fall back to the caller's default.
+// The GetTenant* variants consult hyadmin_tenant_settings first.
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetString(key, def string) string {
+	return s.GetTenantString("", key, def)
+}
+
+func (s *Service) GetInt(key string, def int) int {
+	return s.GetTenantInt("", key, def)
+}
+
+func (s *Service) GetBool(key string, def bool) bool {
+	return s.GetTenantBool("", key, def)
+}
+
+func (s *Service) GetTenantString(tenantCode, key, def string) string {
+	v, ok := s.lookup(tenantCode, key)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+func (s *Service) GetTenantInt(tenantCode, key string, def int) int {
+	v, ok := s.lookup(tenantCode, key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+func (s *Service) GetTenantBool(tenantCode, key string, def bool) bool {
+	v, ok := s.lookup(tenantCode, key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func (s *Service) ListTenant(tenantCode string) ([]TenantSetting, error) {
+	return s.repo.ListTenant(tenantCode)
+}
+
+func (s *Service) PutTenant(tenantCode, key, value, updatedBy string) error {
+	if !TenantOverridable[key] {
+		return fmt.Errorf("setting: %q cannot be overridden per tenant", key)
+	}
+	return s.repo.UpsertTenant(&TenantSetting{
+		TenantCode: tenantCode,
+		Key:        key,
+		Value:      value,
+		UpdatedBy:  updatedBy,
+	})
+}
+
+func (s *Service) DeleteTenant(tenantCode, key string) error {
+	return s.repo.DeleteTenant(tenantCode, key)
+}
+
+func (s *Service) lookup(tenantCode, key string) (string, bool) {
+	if tenantCode != "" {
+		if ts, err := s.repo.FindTenantByKey(tenantCode, key); err == nil {
+			return ts.Value, true
+		}
+	}
+	st, err := s.repo.FindByKey(key)
+	if err != nil {
+		return "", false
+	}
+	return st.Value, true
+}
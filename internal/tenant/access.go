@@ -0,0 +1,64 @@
+package tenant
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robert7528/hycore/middleware"
+)
+
+// SystemCode is the platform tenant. Only its principals holding "*" may act
+// on other tenants; "*" granted in any other tenant stays within it.
+const SystemCode = "system"
+
+// IsPlatformAdmin reports whether a principal of tenantCode holding codes
+// administers every tenant.
+func IsPlatformAdmin(tenantCode string, codes []string) bool {
+	if tenantCode != SystemCode {
+		return false
+	}
+	for _, code := range codes {
+		if code == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess reports whether the caller may act on tenantCode: its own
+// tenant, or any tenant when it is a platform admin.
+// Must run after PermissionLoaderMiddleware.
+func CanAccess(c *gin.Context, tenantCode string) bool {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		return false
+	}
+	return claims.TenantCode == tenantCode || IsPlatformAdmin(claims.TenantCode, middleware.GetPermissionCodes(c))
+}
+
+// CallerCode returns the tenant the caller acts on: requested when given
+// and accessible, else the caller's own tenant. ok is false when requested
+// is another tenant the caller may not access.
+func CallerCode(c *gin.Context, requested string) (code string, ok bool) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		return "", false
+	}
+	if requested == "" {
+		return claims.TenantCode, true
+	}
+	return requested, CanAccess(c, requested)
+}
+
+// RequireAccess aborts with 403 unless the caller may act on the tenant
+// named by the route parameter param.
+func RequireAccess(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CanAccess(c, c.Param(param)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package userbulk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauditlog "github.com/robert7528/hycore/auditlog"
+	"github.com/robert7528/hycore/middleware"
+
+	"github.com/hysp/hyadmin-api/internal/auditlog"
+	"github.com/hysp/hyadmin-api/internal/tenant"
+)
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 10 << 20
+
+type Handler struct {
+	svc   *Service
+	audit *auditlog.Service
+}
+
+func NewHandler(svc *Service, audit *auditlog.Service) *Handler {
+	return &Handler{svc: svc, audit: audit}
+}
+
+// Import POST /api/v1/admin/users/import?tenant_code=...&format=csv|jsonl&dry_run=true&invite=true
+// The file is the multipart field "file" or the raw request body. The format
+// defaults to the file extension or Content-Type; tenant_code to the
+// caller's tenant.
+func (h *Handler) Import(c *gin.Context) {
+	tc, ok := tenant.CallerCode(c, c.Query("tenant_code"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	opts := &ImportOptions{TenantCode: tc}
+	opts.DryRun, _ = strconv.ParseBool(c.Query("dry_run"))
+	opts.Invite, _ = strconv.ParseBool(c.Query("invite"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var body io.Reader = c.Request.Body
+	name := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body, name = f, fh.Filename
+	}
+	format := importFormat(c.Query("format"), name, c.ContentType())
+
+	rows, err := Parse(format, body, h.svc.MaxRows())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTooManyRows):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("at most %d rows per import", h.svc.MaxRows())})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	rep, err := h.svc.Import(c.Request.Context(), opts, rows, actorID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.record(c, "USERS_IMPORT", map[string]interface{}{
+		"tenant_code": opts.TenantCode,
+		"format":      format,
+		"dry_run":     rep.DryRun,
+		"invite":      opts.Invite,
+		"total":       rep.Total,
+		"created":     rep.Created,
+		"failed":      rep.Failed,
+		"invited":     rep.Invited,
+	})
+	c.JSON(http.StatusOK, rep)
+}
+
+// Export GET /api/v1/admin/users/export?tenant_code=...&format=csv|jsonl
+// Streams the tenant's users with decrypted display name and email.
+// tenant_code defaults to the caller's tenant.
+func (h *Handler) Export(c *gin.Context) {
+	tenantCode, ok := tenant.CallerCode(c, c.Query("tenant_code"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	format := c.DefaultQuery("format", FormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case FormatCSV:
+	case FormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFormat.Error()})
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users-%s-%s.%s"`, tenantCode, time.Now().Format("20060102"), format))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	n, err := h.svc.Export(tenantCode, format, c.Writer)
+	detail := map[string]interface{}{"tenant_code": tenantCode, "format": format, "count": n}
+	if err != nil {
+		// Headers are already sent; the truncated file is all the client gets.
+		detail["error"] = err.Error()
+		_ = c.Error(err)
+	}
+	h.record(c, "USERS_EXPORT", detail)
+}
+
+// importFormat picks the format from the query, then the file name, then the
+// Content-Type.
+func importFormat(query, filename, contentType string) string {
+	if query != "" {
+		return strings.ToLower(query)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	switch contentType {
+	case "text/csv":
+		return FormatCSV
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return FormatJSONL
+	}
+	return ""
+}
+
+func (h *Handler) record(c *gin.Context, action string, d map[string]interface{}) {
+	actor := middleware.GetClaims(c)
+	if actor == nil {
+		return
+	}
+	detail, _ := json.Marshal(d)
+	h.audit.Record(&coreauditlog.AuditLog{
+		TenantCode: actor.TenantCode,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		Action:     action,
+		Resource:   "users",
+		Detail:     string(detail),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+}
+
+func actorID(c *gin.Context) uint {
+	if claims := middleware.GetClaims(c); claims != nil {
+		return claims.UserID
+	}
+	return 0
+}
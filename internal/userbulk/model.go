@@ -0,0 +1,106 @@
+package userbulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Columns are the CSV columns understood by import, in export order. Import
+// ignores unknown columns, so an export can be edited and imported again.
+var Columns = []string{"id", "username", "display_name", "email", "provider", "provider_id", "enabled", "mfa_enabled", "roles", "created_at"}
+
+// roleSep separates role names in the CSV roles column.
+const roleSep = ";"
+
+// Row is one user of an import file. Roles are role names or IDs of the tenant.
+type Row struct {
+	Line        int      `json:"-"`
+	Username    string   `json:"username"`
+	DisplayName string   `json:"display_name"`
+	Email       string   `json:"email"`
+	Provider    string   `json:"provider"`
+	ProviderID  string   `json:"provider_id"`
+	Password    string   `json:"password"`
+	Enabled     *bool    `json:"enabled"`
+	Roles       RoleRefs `json:"roles"`
+
+	errs    []string // parse and validation errors
+	roleIDs []uint
+}
+
+// RoleRefs accepts role names and numeric IDs in JSONL.
+type RoleRefs []string
+
+func (r *RoleRefs) UnmarshalJSON(b []byte) error {
+	var raw []interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	refs := make(RoleRefs, 0, len(raw))
+	for _, v := range raw {
+		switch v := v.(type) {
+		case string:
+			refs = append(refs, v)
+		case float64:
+			refs = append(refs, fmt.Sprintf("%.0f", v))
+		default:
+			return fmt.Errorf("roles must be names or IDs")
+		}
+	}
+	*r = refs
+	return nil
+}
+
+// ImportOptions controls POST /admin/users/import.
+type ImportOptions struct {
+	TenantCode string
+	DryRun     bool // validate only
+	Invite     bool // email an invitation to local users imported without a password
+}
+
+// Row statuses.
+const (
+	StatusValid   = "valid" // dry run only
+	StatusCreated = "created"
+	StatusFailed  = "failed"
+)
+
+type RowResult struct {
+	Line        int      `json:"line"`
+	Username    string   `json:"username"`
+	Status      string   `json:"status"`
+	UserID      uint     `json:"user_id,omitempty"`
+	Invited     bool     `json:"invited,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+	InviteError string   `json:"invite_error,omitempty"` // the user was created but the invitation failed
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Valid   int         `json:"valid"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Invited int         `json:"invited"`
+	Rows    []RowResult `json:"rows"`
+}
+
+// Record is one exported user, decrypted.
+type Record struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	Provider    string    `json:"provider"`
+	ProviderID  string    `json:"provider_id"`
+	Enabled     bool      `json:"enabled"`
+	MFAEnabled  bool      `json:"mfa_enabled"`
+	Roles       []string  `json:"roles"`
+	CreatedAt   time.Time `json:"created_at"`
+}
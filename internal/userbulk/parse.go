@@ -0,0 +1,121 @@
+package userbulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrNoUsername = errors.New("userbulk: CSV header has no username column")
+
+// Parse reads an import file. Malformed rows are kept with their errors so
+// they show up in the report; only an unreadable file is an error.
+func Parse(format string, r io.Reader, maxRows int) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, maxRows)
+	case FormatJSONL:
+		return parseJSONL(r, maxRows)
+	}
+	return nil, ErrFormat
+}
+
+func parseCSV(r io.Reader, maxRows int) ([]Row, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("userbulk: read CSV header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff") // spreadsheet BOM
+		}
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["username"]; !ok {
+		return nil, ErrNoUsername
+	}
+
+	var rows []Row
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("userbulk: read CSV: %w", err)
+		}
+		if len(rows) == maxRows {
+			return nil, ErrTooManyRows
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		row := Row{
+			Line:        line,
+			Username:    get("username"),
+			DisplayName: get("display_name"),
+			Email:       get("email"),
+			Provider:    get("provider"),
+			ProviderID:  get("provider_id"),
+			Password:    get("password"),
+		}
+		if v := get("enabled"); v != "" {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				row.errs = append(row.errs, "enabled must be true or false")
+			} else {
+				row.Enabled = &enabled
+			}
+		}
+		for _, ref := range strings.Split(get("roles"), roleSep) {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				row.Roles = append(row.Roles, ref)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseJSONL(r io.Reader, maxRows int) ([]Row, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var rows []Row
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if line == 1 {
+			b = bytes.TrimPrefix(b, []byte("\ufeff"))
+		}
+		if len(b) == 0 {
+			continue
+		}
+		if len(rows) == maxRows {
+			return nil, ErrTooManyRows
+		}
+		var row Row
+		if err := json.Unmarshal(b, &row); err != nil {
+			row = Row{errs: []string{"invalid JSON: " + err.Error()}}
+		}
+		row.Line = line
+		row.Username = strings.TrimSpace(row.Username)
+		row.Email = strings.TrimSpace(row.Email)
+		rows = append(rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("userbulk: read JSONL: %w", err)
+	}
+	return rows, nil
+}
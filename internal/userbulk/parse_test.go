@@ -0,0 +1,147 @@
+package userbulk
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name  string
+		input string
+		want  []Row
+	}{
+		{"header only", "username\n", nil},
+		{"minimal", "username\nalice\n", []Row{{Line: 2, Username: "alice"}}},
+		{
+			"all columns",
+			"username,display_name,email,provider,provider_id,password,enabled,roles\n" +
+				"alice,Alice Example,alice@example.com,local,,S3cret-pass,true,admin; 7 ;;viewer\n",
+			[]Row{{Line: 2, Username: "alice", DisplayName: "Alice Example", Email: "alice@example.com",
+				Provider: "local", Password: "S3cret-pass", Enabled: &yes, Roles: RoleRefs{"admin", "7", "viewer"}}},
+		},
+		{"bom and header case", "\ufeffUserName , Email\nalice,alice@example.com\n",
+			[]Row{{Line: 2, Username: "alice", Email: "alice@example.com"}}},
+		{"column order and unknown columns", "id,email,username,created_at\n9,b@example.com,bob,2026-01-01\n",
+			[]Row{{Line: 2, Username: "bob", Email: "b@example.com"}}},
+		{"trimmed", "username,email\n  alice  ,  alice@example.com \n",
+			[]Row{{Line: 2, Username: "alice", Email: "alice@example.com"}}},
+		{"short record", "username,email\nalice\n", []Row{{Line: 2, Username: "alice"}}},
+		{"disabled", "username,enabled\nalice,FALSE\n", []Row{{Line: 2, Username: "alice", Enabled: &no}}},
+		{"bad enabled", "username,enabled\nalice,maybe\n",
+			[]Row{{Line: 2, Username: "alice", errs: []string{"enabled must be true or false"}}}},
+		{"line of multi-line record", "username,display_name\nalice,\"Alice\nExample\"\nbob,Bob\n",
+			[]Row{{Line: 2, Username: "alice", DisplayName: "Alice\nExample"}, {Line: 4, Username: "bob", DisplayName: "Bob"}}},
+		{"empty username kept", "username,email\n,x@example.com\n", []Row{{Line: 2, Email: "x@example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(FormatCSV, strings.NewReader(tt.input), 10)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONL(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name  string
+		input string
+		want  []Row
+	}{
+		{"empty", "", nil},
+		{"minimal", `{"username":"alice"}`, []Row{{Line: 1, Username: "alice"}}},
+		{
+			"all fields",
+			`{"username":" alice ","display_name":"Alice","email":" alice@example.com","provider":"oidc","provider_id":"00u1","enabled":true,"roles":["admin",7]}`,
+			[]Row{{Line: 1, Username: "alice", DisplayName: "Alice", Email: "alice@example.com",
+				Provider: "oidc", ProviderID: "00u1", Enabled: &yes, Roles: RoleRefs{"admin", "7"}}},
+		},
+		{"bom and blank lines", "\ufeff{\"username\":\"a\"}\n\n  \n{\"username\":\"b\"}\n",
+			[]Row{{Line: 1, Username: "a"}, {Line: 4, Username: "b"}}},
+		{"unknown fields ignored", `{"username":"alice","id":3,"mfa_enabled":true}`, []Row{{Line: 1, Username: "alice"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(FormatJSONL, strings.NewReader(tt.input), 10)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseJSONLInvalid checks that a broken line becomes a failed row at
+// its line number instead of failing the whole file.
+func TestParseJSONLInvalid(t *testing.T) {
+	input := "{\"username\":\"a\"}\n{\"username\":\n{\"username\":\"c\",\"roles\":[true]}\n{\"username\":\"d\",\"enabled\":\"yes\"}\n"
+	rows, err := Parse(FormatJSONL, strings.NewReader(input), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+	for i, row := range rows {
+		if row.Line != i+1 {
+			t.Errorf("row %d: line %d, want %d", i, row.Line, i+1)
+		}
+		if failed := len(row.errs) > 0; failed != (i > 0) {
+			t.Errorf("line %d: errors %q", row.Line, row.errs)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, format, input string
+		want                error
+	}{
+		{"unknown format", "xlsx", "username\nalice\n", ErrFormat},
+		{"no username column", FormatCSV, "user,email\nalice,a@example.com\n", ErrNoUsername},
+		{"csv too many rows", FormatCSV, "username\na\nb\nc\n", ErrTooManyRows},
+		{"jsonl too many rows", FormatJSONL, "{}\n{}\n\n{}\n", ErrTooManyRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.format, strings.NewReader(tt.input), 2); !errors.Is(err, tt.want) {
+				t.Errorf("Parse = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, err := Parse(FormatCSV, strings.NewReader(""), 2); err == nil {
+		t.Error("Parse of an empty CSV: want an error")
+	}
+	if rows, err := Parse(FormatCSV, strings.NewReader("username\na\nb\n"), 2); err != nil || len(rows) != 2 {
+		t.Errorf("Parse at the row limit = %d rows, %v; want 2 rows", len(rows), err)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		query, filename, contentType, want string
+	}{
+		{"CSV", "users.jsonl", "", FormatCSV},
+		{"", "users.CSV", "application/x-ndjson", FormatCSV},
+		{"", "users.ndjson", "", FormatJSONL},
+		{"", "upload", "text/csv", FormatCSV},
+		{"", "upload", "application/jsonl", FormatJSONL},
+		{"", "users.xlsx", "application/octet-stream", ""},
+	}
+	for _, tt := range tests {
+		if got := importFormat(tt.query, tt.filename, tt.contentType); got != tt.want {
+			t.Errorf("importFormat(%q, %q, %q) = %q, want %q", tt.query, tt.filename, tt.contentType, got, tt.want)
+		}
+	}
+}
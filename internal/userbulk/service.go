@@ -0,0 +1,290 @@
+// Package userbulk imports admin users from CSV/JSONL files and exports a
+// tenant's users, decrypted, in the same formats.
+package userbulk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/hysp/hyadmin-api/internal/adminuser"
+	"github.com/hysp/hyadmin-api/internal/invitation"
+	"github.com/hysp/hyadmin-api/internal/password"
+	"github.com/hysp/hyadmin-api/internal/role"
+	"github.com/hysp/hyadmin-api/internal/setting"
+)
+
+var (
+	ErrFormat      = errors.New("userbulk: format must be csv or jsonl")
+	ErrTooManyRows = errors.New("userbulk: too many rows")
+)
+
+const exportBatch = 500
+
+type Service struct {
+	users       *adminuser.Service
+	roles       *role.Service
+	invitations *invitation.Service
+	passwords   *password.Service
+	settings    *setting.Service
+}
+
+func NewService(users *adminuser.Service, roles *role.Service, invitations *invitation.Service, passwords *password.Service, settings *setting.Service) *Service {
+	return &Service{users: users, roles: roles, invitations: invitations, passwords: passwords, settings: settings}
+}
+
+// MaxRows is the largest import a single request may contain.
+func (s *Service) MaxRows() int {
+	return s.settings.GetInt("users.import.max_rows", 5000)
+}
+
+// Import validates every row and, unless opts.DryRun, creates the valid ones.
+// A failing row does not stop the others; each row's outcome is reported.
+func (s *Service) Import(ctx context.Context, opts *ImportOptions, rows []Row, invitedBy uint) (*ImportReport, error) {
+	if err := s.validate(opts, rows); err != nil {
+		return nil, err
+	}
+	rep := &ImportReport{DryRun: opts.DryRun, Total: len(rows), Rows: make([]RowResult, 0, len(rows))}
+	for i := range rows {
+		row := &rows[i]
+		res := RowResult{Line: row.Line, Username: row.Username, Errors: row.errs}
+		switch {
+		case len(row.errs) > 0:
+			res.Status = StatusFailed
+		case opts.DryRun:
+			res.Status = StatusValid
+		default:
+			s.create(ctx, opts, row, invitedBy, &res)
+		}
+		switch res.Status {
+		case StatusValid:
+			rep.Valid++
+		case StatusCreated:
+			rep.Valid++
+			rep.Created++
+		case StatusFailed:
+			rep.Failed++
+		}
+		if res.Invited {
+			rep.Invited++
+		}
+		rep.Rows = append(rep.Rows, res)
+	}
+	return rep, nil
+}
+
+// validate records the problems of each row in row.errs and resolves its roles.
+func (s *Service) validate(opts *ImportOptions, rows []Row) error {
+	tenantRoles, err := s.roles.List(opts.TenantCode)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]uint, len(tenantRoles))
+	byID := make(map[uint]bool, len(tenantRoles))
+	for _, r := range tenantRoles {
+		byName[r.Name] = r.ID
+		byID[r.ID] = true
+	}
+
+	seen := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if row.Provider == "" {
+			row.Provider = "local"
+		}
+		if checkRow(row, opts.Invite, seen, byName, byID) {
+			if _, err := s.users.GetByUsername(opts.TenantCode, row.Username); err == nil {
+				row.errs = append(row.errs, "username already exists")
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if row.Password != "" && row.Provider == "local" {
+			if err := s.passwords.Validate(opts.TenantCode, row.Username, row.Password); err != nil {
+				row.errs = append(row.errs, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// checkRow runs the checks that need no database: username format and
+// uniqueness within the file, email syntax, invitation prerequisites and role
+// references, which it resolves into row.roleIDs. It reports whether the
+// username is well-formed and new to the file, i.e. worth looking up.
+func checkRow(row *Row, invite bool, seen map[string]int, byName map[string]uint, byID map[uint]bool) bool {
+	fail := func(format string, args ...interface{}) { row.errs = append(row.errs, fmt.Sprintf(format, args...)) }
+
+	lookup := false
+	switch {
+	case row.Username == "":
+		fail("username is required")
+	case strings.ContainsAny(row.Username, " \t\r\n"):
+		fail("username must not contain whitespace")
+	case seen[row.Username] != 0:
+		fail("duplicate username, first on line %d", seen[row.Username])
+	default:
+		seen[row.Username] = row.Line
+		lookup = true
+	}
+	if row.Email != "" {
+		if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+			fail("invalid email")
+		}
+	}
+	if row.Password != "" && row.Provider != "local" {
+		fail("password is only allowed for local users")
+	}
+	if invite && row.Provider == "local" && row.Password == "" {
+		if row.Email == "" {
+			fail("email is required to send an invitation")
+		}
+		if row.Enabled != nil && !*row.Enabled {
+			fail("cannot invite a disabled user")
+		}
+	}
+	for _, ref := range row.Roles {
+		id, ok := byName[ref]
+		if !ok {
+			if n, err := strconv.ParseUint(ref, 10, 64); err == nil && byID[uint(n)] {
+				id, ok = uint(n), true
+			}
+		}
+		if !ok {
+			fail("unknown role %q", ref)
+			continue
+		}
+		row.roleIDs = append(row.roleIDs, id)
+	}
+	return lookup
+}
+
+func (s *Service) create(ctx context.Context, opts *ImportOptions, row *Row, invitedBy uint, res *RowResult) {
+	u, err := s.users.Create(&adminuser.CreateUserRequest{
+		TenantCode:  opts.TenantCode,
+		Username:    row.Username,
+		Password:    row.Password,
+		DisplayName: row.DisplayName,
+		Email:       row.Email,
+		Provider:    row.Provider,
+		ProviderID:  row.ProviderID,
+	})
+	if err != nil {
+		res.Status = StatusFailed
+		res.Errors = append(res.Errors, err.Error())
+		return
+	}
+	res.Status = StatusCreated
+	res.UserID = u.ID
+	// The user exists from here on; later failures are reported on the row
+	// without failing it, so that a retry does not collide with the username.
+	if row.Enabled != nil && !*row.Enabled {
+		if err := s.users.Update(u.ID, &adminuser.UpdateUserRequest{Enabled: row.Enabled}); err != nil {
+			res.Errors = append(res.Errors, "disable: "+err.Error())
+		}
+	}
+	if len(row.roleIDs) > 0 {
+		if err := s.roles.AssignRolesToUser(opts.TenantCode, u.ID, row.roleIDs); err != nil {
+			res.Errors = append(res.Errors, "assign roles: "+err.Error())
+		}
+	}
+	if opts.Invite && row.Provider == "local" && row.Password == "" {
+		if _, err := s.invitations.Invite(ctx, u.ID, invitedBy); err != nil {
+			res.InviteError = err.Error()
+		} else {
+			res.Invited = true
+		}
+	}
+}
+
+// Export streams every user of a tenant to w, decrypted, and returns how many
+// were written.
+func (s *Service) Export(tenantCode, format string, w io.Writer) (int, error) {
+	if format != FormatCSV && format != FormatJSONL {
+		return 0, ErrFormat
+	}
+	tenantRoles, err := s.roles.List(tenantCode)
+	if err != nil {
+		return 0, err
+	}
+	roleNames := make(map[uint]string, len(tenantRoles))
+	for _, r := range tenantRoles {
+		roleNames[r.ID] = r.Name
+	}
+
+	var cw *csv.Writer
+	enc := json.NewEncoder(w)
+	if format == FormatCSV {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(Columns); err != nil {
+			return 0, err
+		}
+	}
+	var afterID uint
+	n := 0
+	for {
+		users, err := s.users.ListTenantBatch(tenantCode, afterID, exportBatch)
+		if err != nil {
+			return n, err
+		}
+		if len(users) == 0 {
+			break
+		}
+		for _, u := range users {
+			afterID = u.ID
+			rec := Record{
+				ID:          u.ID,
+				Username:    u.Username,
+				DisplayName: u.DisplayName,
+				Email:       u.Email,
+				Provider:    u.Provider,
+				ProviderID:  u.ProviderID,
+				Enabled:     u.Enabled,
+				MFAEnabled:  u.MFAEnabled,
+				Roles:       []string{},
+				CreatedAt:   u.CreatedAt,
+			}
+			ids, err := s.roles.GetRolesForUser(tenantCode, u.ID)
+			if err != nil {
+				return n, err
+			}
+			for _, id := range ids {
+				if name, ok := roleNames[id]; ok {
+					rec.Roles = append(rec.Roles, name)
+				}
+			}
+			if cw != nil {
+				err = cw.Write([]string{
+					strconv.FormatUint(uint64(rec.ID), 10), rec.Username, rec.DisplayName, rec.Email,
+					rec.Provider, rec.ProviderID, strconv.FormatBool(rec.Enabled), strconv.FormatBool(rec.MFAEnabled),
+					strings.Join(rec.Roles, roleSep), rec.CreatedAt.Format(time.RFC3339),
+				})
+			} else {
+				err = enc.Encode(rec)
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if cw != nil {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return n, err
+			}
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+	return n, nil
+}
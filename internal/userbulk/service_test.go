@@ -0,0 +1,60 @@
+package userbulk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckRow(t *testing.T) {
+	no := false
+	byName := map[string]uint{"admin": 1, "viewer": 2, "42": 3}
+	byID := map[uint]bool{1: true, 2: true, 3: true}
+	tests := []struct {
+		name       string
+		row        Row
+		invite     bool
+		wantLookup bool
+		wantErrs   []string
+		wantRoles  []uint
+	}{
+		{"valid", Row{Username: "alice", Provider: "local"}, false, true, nil, nil},
+		{"no username", Row{Provider: "local"}, false, false, []string{"username is required"}, nil},
+		{"whitespace", Row{Username: "al ice", Provider: "local"}, false, false, []string{"username must not contain whitespace"}, nil},
+		{"duplicate", Row{Username: "taken", Provider: "local"}, false, false, []string{"duplicate username, first on line 2"}, nil},
+		{"email", Row{Username: "alice", Provider: "local", Email: "alice@example.com"}, false, true, nil, nil},
+		{"bad email", Row{Username: "alice", Provider: "local", Email: "not an address"}, false, true, []string{"invalid email"}, nil},
+		{"email with name", Row{Username: "alice", Provider: "local", Email: "Alice <alice@example.com>"}, false, true, []string{"invalid email"}, nil},
+		{"password for external user", Row{Username: "alice", Provider: "oidc", Password: "x"}, false, true, []string{"password is only allowed for local users"}, nil},
+		{"invite", Row{Username: "alice", Provider: "local", Email: "alice@example.com"}, true, true, nil, nil},
+		{"invite without email", Row{Username: "alice", Provider: "local"}, true, true, []string{"email is required to send an invitation"}, nil},
+		{"invite disabled", Row{Username: "alice", Provider: "local", Email: "alice@example.com", Enabled: &no}, true, true, []string{"cannot invite a disabled user"}, nil},
+		{"no invite with password", Row{Username: "alice", Provider: "local", Password: "x"}, true, true, nil, nil},
+		{"no invite for external", Row{Username: "alice", Provider: "ldap"}, true, true, nil, nil},
+		{"roles by name and id", Row{Username: "alice", Provider: "local", Roles: RoleRefs{"viewer", "1"}}, false, true, nil, []uint{2, 1}},
+		{"name before id", Row{Username: "alice", Provider: "local", Roles: RoleRefs{"42"}}, false, true, nil, []uint{3}},
+		{"unknown roles", Row{Username: "alice", Provider: "local", Roles: RoleRefs{"Admin", "9", "admin"}}, false, true,
+			[]string{`unknown role "Admin"`, `unknown role "9"`}, []uint{1}},
+		{"every error", Row{Provider: "oidc", Email: "x", Password: "x", Roles: RoleRefs{"nope"}}, true, false,
+			[]string{"username is required", "invalid email", "password is only allowed for local users", `unknown role "nope"`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := map[string]int{"taken": 2}
+			row := tt.row
+			row.Line = 5
+			lookup := checkRow(&row, tt.invite, seen, byName, byID)
+			if lookup != tt.wantLookup {
+				t.Errorf("lookup = %v, want %v", lookup, tt.wantLookup)
+			}
+			if !reflect.DeepEqual(row.errs, tt.wantErrs) {
+				t.Errorf("errors = %q, want %q", row.errs, tt.wantErrs)
+			}
+			if !reflect.DeepEqual(row.roleIDs, tt.wantRoles) {
+				t.Errorf("roles = %v, want %v", row.roleIDs, tt.wantRoles)
+			}
+			if lookup && seen[row.Username] != row.Line {
+				t.Errorf("username not remembered for the duplicate check")
+			}
+		})
+	}
+}
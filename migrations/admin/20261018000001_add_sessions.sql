@@ -0,0 +1,23 @@
+-- Atlas migration: add server-side sessions
+-- Generated: 2026-10-18
+-- Purpose: Back short-lived access tokens with revocable sessions and rotating refresh tokens.
+
+CREATE TABLE IF NOT EXISTS hyadmin_sessions (
+    id                 VARCHAR(64)  PRIMARY KEY,
+    user_id            BIGINT       NOT NULL,
+    tenant_code        VARCHAR(100) NOT NULL,
+    username           VARCHAR(255) NOT NULL,
+    provider           VARCHAR(50)  NOT NULL,
+    refresh_token_hash VARCHAR(64)  NOT NULL,
+    ip                 VARCHAR(50),
+    user_agent         TEXT,
+    expires_at         TIMESTAMPTZ  NOT NULL,
+    last_used_at       TIMESTAMPTZ,
+    revoked_at         TIMESTAMPTZ,
+    revoke_reason      VARCHAR(50),
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_sessions_user_id     ON hyadmin_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_sessions_tenant_code ON hyadmin_sessions (tenant_code);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_sessions_expires_at  ON hyadmin_sessions (expires_at);
@@ -0,0 +1,32 @@
+-- Atlas migration: add TOTP multi-factor authentication
+-- Generated: 2026-10-18
+-- Purpose: TOTP secret + recovery codes for local users, per-tenant setting overrides (auth.mfa.required).
+
+ALTER TABLE hyadmin_users    ADD COLUMN IF NOT EXISTS totp_secret    TEXT;
+ALTER TABLE hyadmin_users    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT  NOT NULL DEFAULT 0;
+ALTER TABLE hyadmin_users    ADD COLUMN IF NOT EXISTS mfa_enabled    BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE hyadmin_sessions ADD COLUMN IF NOT EXISTS scope          VARCHAR(50);
+
+-- ─────────────────────────────────────────────
+-- MFA recovery codes (SHA-256 hashed, single use)
+-- ─────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS hyadmin_mfa_recovery_codes (
+    id         BIGSERIAL   PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_mfa_recovery_codes_user_id ON hyadmin_mfa_recovery_codes (user_id);
+
+-- ─────────────────────────────────────────────
+-- Per-tenant overrides of hyadmin_settings
+-- ─────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS hyadmin_tenant_settings (
+    tenant_code VARCHAR(100) NOT NULL,
+    key         VARCHAR(255) NOT NULL,
+    value       TEXT         NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ,
+    updated_by  VARCHAR(255),
+    PRIMARY KEY (tenant_code, key)
+);
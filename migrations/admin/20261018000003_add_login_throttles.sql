@@ -0,0 +1,10 @@
+-- Atlas migration: add login throttles
+-- Generated: 2026-10-18
+-- Purpose: Failed-login counters and lockouts per user ("user:{tenant}/{username}") and per source IP ("ip:{addr}").
+
+CREATE TABLE IF NOT EXISTS hyadmin_login_throttles (
+    key             VARCHAR(400) PRIMARY KEY,
+    failures        INTEGER      NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ,
+    locked_until    TIMESTAMPTZ
+);
@@ -0,0 +1,20 @@
+-- Atlas migration: add password history and forced password change
+-- Generated: 2026-10-18
+-- Purpose: Reject reuse of recent passwords, track password age, and flag users who must change their password on next login.
+
+ALTER TABLE hyadmin_users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE hyadmin_users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ;
+
+-- Existing passwords start ageing from their last update rather than expiring immediately.
+UPDATE hyadmin_users
+SET password_changed_at = COALESCE(updated_at, created_at)
+WHERE password_changed_at IS NULL AND password_hash <> '';
+
+CREATE TABLE IF NOT EXISTS hyadmin_password_history (
+    id            BIGSERIAL    PRIMARY KEY,
+    user_id       BIGINT       NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_password_history_user_id ON hyadmin_password_history (user_id);
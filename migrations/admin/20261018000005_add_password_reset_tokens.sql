@@ -0,0 +1,16 @@
+-- Atlas migration: add password reset tokens
+-- Generated: 2026-10-18
+-- Purpose: Single-use, expiring self-service password reset tokens (SHA-256 hashed).
+
+CREATE TABLE IF NOT EXISTS hyadmin_password_reset_tokens (
+    id         BIGSERIAL   PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    ip         VARCHAR(64),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_password_reset_tokens_token_hash ON hyadmin_password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_password_reset_tokens_user_id ON hyadmin_password_reset_tokens (user_id);
@@ -0,0 +1,36 @@
+-- Atlas migration: add OIDC providers
+-- Generated: 2026-10-18
+-- Purpose: Per-tenant OpenID Connect identity providers and in-flight authorization-code + PKCE login state.
+
+CREATE TABLE IF NOT EXISTS hyadmin_oidc_providers (
+    id                  BIGSERIAL    PRIMARY KEY,
+    tenant_code         VARCHAR(100) NOT NULL,
+    name                VARCHAR(100) NOT NULL,
+    display_name        VARCHAR(200),
+    issuer_url          TEXT         NOT NULL,
+    client_id           TEXT         NOT NULL,
+    client_secret       TEXT,
+    redirect_url        TEXT         NOT NULL,
+    scopes              TEXT         DEFAULT 'openid profile email',
+    post_login_redirect TEXT,
+    enabled             BOOLEAN      DEFAULT true,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_oidc_tenant_name ON hyadmin_oidc_providers (tenant_code, name);
+
+CREATE TABLE IF NOT EXISTS hyadmin_oidc_states (
+    state_hash  VARCHAR(64)  PRIMARY KEY,
+    tenant_code VARCHAR(100) NOT NULL,
+    provider    VARCHAR(100) NOT NULL,
+    verifier    TEXT         NOT NULL,
+    nonce       TEXT         NOT NULL,
+    expires_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_oidc_states_expires_at ON hyadmin_oidc_states (expires_at);
+
+-- Lookups of external identities by (tenant, provider, subject).
+CREATE INDEX IF NOT EXISTS idx_hyadmin_users_provider_id ON hyadmin_users (tenant_code, provider, provider_id);
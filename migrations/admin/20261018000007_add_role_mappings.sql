@@ -0,0 +1,15 @@
+-- Atlas migration: add role mappings
+-- Generated: 2026-10-18
+-- Purpose: Per-tenant rules mapping external IdP groups/claims to roles for JIT-provisioned users.
+
+CREATE TABLE IF NOT EXISTS hyadmin_role_mappings (
+    id          BIGSERIAL    PRIMARY KEY,
+    tenant_code VARCHAR(100) NOT NULL,
+    provider    VARCHAR(100) NOT NULL DEFAULT '*',
+    claim       VARCHAR(200) NOT NULL,
+    value       TEXT         NOT NULL,
+    role_id     BIGINT       NOT NULL,
+    created_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_role_mappings_tenant_code ON hyadmin_role_mappings (tenant_code);
@@ -0,0 +1,22 @@
+-- Atlas migration: add LDAP configs
+-- Generated: 2026-10-18
+-- Purpose: Per-tenant LDAP / Active Directory bind authentication settings.
+
+CREATE TABLE IF NOT EXISTS hyadmin_ldap_configs (
+    id                BIGSERIAL    PRIMARY KEY,
+    tenant_code       VARCHAR(100) NOT NULL,
+    url               TEXT         NOT NULL,
+    bind_dn_template  TEXT         NOT NULL,
+    search_base       TEXT         NOT NULL,
+    user_filter       TEXT         DEFAULT '(uid={username})',
+    group_attr        VARCHAR(100) DEFAULT 'memberOf',
+    display_name_attr VARCHAR(100) DEFAULT 'displayName',
+    email_attr        VARCHAR(100) DEFAULT 'mail',
+    start_tls         BOOLEAN      DEFAULT false,
+    insecure_skip_tls BOOLEAN      DEFAULT false,
+    enabled           BOOLEAN      DEFAULT true,
+    created_at        TIMESTAMPTZ,
+    updated_at        TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_ldap_configs_tenant_code ON hyadmin_ldap_configs (tenant_code);
@@ -0,0 +1,21 @@
+-- Atlas migration: add personal access tokens
+-- Generated: 2026-10-18
+-- Purpose: Hashed, scoped, expiring "hya_" API tokens for scripting against the admin API.
+
+CREATE TABLE IF NOT EXISTS hyadmin_api_tokens (
+    id           BIGSERIAL    PRIMARY KEY,
+    user_id      BIGINT       NOT NULL,
+    tenant_code  VARCHAR(100) NOT NULL,
+    name         VARCHAR(200) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL,
+    codes        TEXT         NOT NULL,
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(64),
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_api_tokens_token_hash ON hyadmin_api_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_api_tokens_user_id ON hyadmin_api_tokens (user_id);
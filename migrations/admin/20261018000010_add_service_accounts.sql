@@ -0,0 +1,21 @@
+-- Atlas migration: add service accounts
+-- Generated: 2026-10-18
+-- Purpose: Non-human principals per tenant, authenticated with OAuth2 client credentials; roles via Casbin subject "svc:<id>".
+
+CREATE TABLE IF NOT EXISTS hyadmin_service_accounts (
+    id           BIGSERIAL    PRIMARY KEY,
+    tenant_code  VARCHAR(100) NOT NULL,
+    name         VARCHAR(200) NOT NULL,
+    description  TEXT,
+    client_id    VARCHAR(64)  NOT NULL,
+    secret_hash  VARCHAR(64)  NOT NULL,
+    enabled      BOOLEAN      DEFAULT true,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_service_accounts_client_id ON hyadmin_service_accounts (client_id);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_service_accounts_tenant_code ON hyadmin_service_accounts (tenant_code);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_service_accounts_deleted_at ON hyadmin_service_accounts (deleted_at);
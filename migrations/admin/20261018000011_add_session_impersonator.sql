@@ -0,0 +1,5 @@
+-- Atlas migration: add session impersonator
+-- Generated: 2026-10-18
+-- Purpose: Mark sessions opened by an administrator impersonating the session's user.
+
+ALTER TABLE hyadmin_sessions ADD COLUMN IF NOT EXISTS impersonator_id BIGINT;
@@ -0,0 +1,18 @@
+-- Atlas migration: add scim tokens
+-- Generated: 2026-10-18
+-- Purpose: Per-tenant bearer tokens for SCIM 2.0 user/group provisioning (SHA-256 hash only).
+
+CREATE TABLE IF NOT EXISTS hyadmin_scim_tokens (
+    id           BIGSERIAL    PRIMARY KEY,
+    tenant_code  VARCHAR(100) NOT NULL,
+    name         VARCHAR(200) NOT NULL,
+    provider     VARCHAR(100) NOT NULL DEFAULT 'scim',
+    prefix       VARCHAR(16)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_scim_tokens_token_hash ON hyadmin_scim_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_scim_tokens_tenant_code ON hyadmin_scim_tokens (tenant_code);
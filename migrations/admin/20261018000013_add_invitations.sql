@@ -0,0 +1,22 @@
+-- Atlas migration: add invitations
+-- Generated: 2026-10-18
+-- Purpose: Single-use emailed invitations that let new local users choose their own password (SHA-256 hash only).
+
+CREATE TABLE IF NOT EXISTS hyadmin_invitations (
+    id          BIGSERIAL    PRIMARY KEY,
+    user_id     BIGINT       NOT NULL,
+    tenant_code VARCHAR(100) NOT NULL,
+    status      VARCHAR(20)  NOT NULL,
+    token_hash  VARCHAR(64)  NOT NULL,
+    invited_by  BIGINT,
+    sent_count  BIGINT       DEFAULT 0,
+    expires_at  TIMESTAMPTZ,
+    accepted_at TIMESTAMPTZ,
+    revoked_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_invitations_user_id ON hyadmin_invitations (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hyadmin_invitations_token_hash ON hyadmin_invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_invitations_tenant_code ON hyadmin_invitations (tenant_code);
@@ -0,0 +1,9 @@
+-- Atlas migration: add user blind indexes
+-- Generated: 2026-10-18
+-- Purpose: Keyed HMAC blind indexes so encrypted email/display name can be searched; fill with `hyadmin users reindex`.
+
+ALTER TABLE hyadmin_users ADD COLUMN IF NOT EXISTS email_bidx VARCHAR(64);
+ALTER TABLE hyadmin_users ADD COLUMN IF NOT EXISTS search_bidx TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_users_email_bidx ON hyadmin_users (email_bidx);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_users_search_bidx ON hyadmin_users USING GIN (string_to_array(search_bidx, ' '));
@@ -0,0 +1,11 @@
+-- Atlas migration: add key rotation progress
+-- Generated: 2026-10-18
+-- Purpose: Resume points of `hyadmin crypto rotate`, one row per encrypted column.
+
+CREATE TABLE IF NOT EXISTS hyadmin_key_rotation_progress (
+    target     VARCHAR(200) PRIMARY KEY,
+    key_id     BIGINT       NOT NULL,
+    last_id    BIGINT       NOT NULL,
+    rotated    BIGINT       NOT NULL,
+    updated_at TIMESTAMPTZ
+);
@@ -0,0 +1,40 @@
+-- Atlas migration: casbin tenant domains
+-- Generated: 2026-10-18
+-- Purpose: Move hyadmin_casbin_rules to RBAC with domains (tenant code): p = sub, dom, obj, act and g = sub, role, dom.
+-- The table is created by the Casbin adapter on first start, so a fresh install has nothing to rewrite.
+
+DO $$
+BEGIN
+    IF to_regclass('hyadmin_casbin_rules') IS NULL THEN
+        RETURN;
+    END IF;
+
+    -- p: (role:N, obj, act) -> (role:N, <tenant of role N>, obj, act)
+    UPDATE hyadmin_casbin_rules c
+    SET v1 = r.tenant_code, v2 = c.v1, v3 = c.v2
+    FROM hyadmin_roles r
+    WHERE c.ptype = 'p'
+      AND COALESCE(c.v3, '') = ''
+      AND c.v0 = 'role:' || r.id;
+
+    -- g: (sub, role:N) -> (sub, role:N, <tenant of role N>)
+    UPDATE hyadmin_casbin_rules c
+    SET v2 = r.tenant_code
+    FROM hyadmin_roles r
+    WHERE c.ptype = 'g'
+      AND COALESCE(c.v2, '') = ''
+      AND c.v1 = 'role:' || r.id;
+
+    -- Rules of roles that no longer exist cannot be placed in a domain.
+    DELETE FROM hyadmin_casbin_rules WHERE ptype = 'p' AND COALESCE(v3, '') = '';
+    DELETE FROM hyadmin_casbin_rules WHERE ptype = 'g' AND COALESCE(v2, '') = '';
+
+    -- Cross-tenant assignments used to grant access in another tenant; drop them.
+    DELETE FROM hyadmin_casbin_rules c
+    USING hyadmin_users u
+    WHERE c.ptype = 'g' AND c.v0 = 'user:' || u.id AND c.v2 <> u.tenant_code;
+
+    DELETE FROM hyadmin_casbin_rules c
+    USING hyadmin_service_accounts a
+    WHERE c.ptype = 'g' AND c.v0 = 'svc:' || a.id AND c.v2 <> a.tenant_code;
+END $$;
@@ -0,0 +1,16 @@
+-- Atlas migration: casbin policy effect
+-- Generated: 2026-10-18
+-- Purpose: Add the effect column to p rules (p = sub, dom, obj, act, eft); existing policies become allows.
+-- The table is created by the Casbin adapter on first start, so a fresh install has nothing to rewrite.
+
+DO $$
+BEGIN
+    IF to_regclass('hyadmin_casbin_rules') IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE hyadmin_casbin_rules
+    SET v4 = 'allow'
+    WHERE ptype = 'p'
+      AND COALESCE(v4, '') = '';
+END $$;
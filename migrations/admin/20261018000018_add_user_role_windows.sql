@@ -0,0 +1,21 @@
+-- Atlas migration: add user role windows
+-- Generated: 2026-10-18
+-- Purpose: Time-bound role assignments. hyadmin_user_roles holds the validity window of a user's
+-- assignment; the expiry job adds the Casbin g policy when valid_from arrives (active) and removes
+-- it, with the row, once valid_until has passed.
+
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS tenant_code VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS valid_from  TIMESTAMPTZ;
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS valid_until TIMESTAMPTZ;
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS active      BOOLEAN     NOT NULL DEFAULT TRUE;
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS granted_by  BIGINT;
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS created_at  TIMESTAMPTZ;
+ALTER TABLE hyadmin_user_roles ADD COLUMN IF NOT EXISTS updated_at  TIMESTAMPTZ;
+
+UPDATE hyadmin_user_roles ur
+SET tenant_code = r.tenant_code
+FROM hyadmin_roles r
+WHERE ur.role_id = r.id
+  AND ur.tenant_code = '';
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_user_roles_valid_until ON hyadmin_user_roles (valid_until);
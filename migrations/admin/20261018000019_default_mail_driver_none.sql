@@ -0,0 +1,11 @@
+-- Atlas migration: default mail driver none
+-- Generated: 2026-10-18
+-- Purpose: The seeded mail.driver "log" wrote working reset and invitation links to the application log.
+-- Switch installs still on the seeded value (never changed by an admin) to "none", which refuses to send.
+
+UPDATE hyadmin_settings
+SET value = 'none',
+    description = '寄信方式：none（不寄信）| log（僅限開發，信件內容含連結會寫入日誌）| file | smtp'
+WHERE key = 'mail.driver'
+  AND value = 'log'
+  AND COALESCE(updated_by, '') = '';
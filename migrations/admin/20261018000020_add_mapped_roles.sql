@@ -0,0 +1,15 @@
+-- Atlas migration: add mapped roles
+-- Generated: 2026-10-18
+-- Purpose: Record which user roles were granted by role mappings, so provisioning revokes exactly
+-- those on login once no rule grants them any more, even after the rule itself was deleted.
+-- Grants made before this migration are treated as manual and are never revoked by a sync.
+
+CREATE TABLE IF NOT EXISTS hyadmin_mapped_roles (
+    user_id     BIGINT       NOT NULL,
+    role_id     BIGINT       NOT NULL,
+    tenant_code VARCHAR(100) NOT NULL,
+    created_at  TIMESTAMPTZ,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_hyadmin_mapped_roles_tenant_code ON hyadmin_mapped_roles (tenant_code);
@@ -0,0 +1,11 @@
+-- Atlas migration: add session rotated tokens
+-- Generated: 2026-10-18
+-- Purpose: Keep the hashes of refresh tokens superseded by rotation. Presenting one of them again
+-- revokes the session as a leaked token; any other wrong secret is only rejected.
+
+CREATE TABLE IF NOT EXISTS hyadmin_session_rotated_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL,
+    rotated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_hyadmin_session_rotated_tokens_session_id ON hyadmin_session_rotated_tokens (session_id);